@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"terminalui/kubeutils"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/kubernetes"
+)
+
+// runGarbageCollector lists load generator pods across all namespaces and deletes the stale ones.
+// It is used for pods left behind by sessions that were quit before the cleanup step.
+func runGarbageCollector(args []string) {
+	fs := flag.NewFlagSet("gc", flag.ExitOnError)
+	kubeCtx := fs.String("context", "", "kubeconfig context to use. Defaults to the current context")
+	kubeconfig := fs.String("kubeconfig", "", "path to the kubeconfig file. Defaults to $KUBECONFIG (merged) or ~/.kube/config")
+	olderThan := fs.Duration("older-than", 0, "only delete pods older than this, e.g. '2h'. 0 deletes pods of any age")
+	selector := fs.String("selector", "", "additional label selector, e.g. 'team=perf'")
+	prefix := fs.String("prefix", "", "only consider pods of sessions with this pod prefix")
+	dryRun := fs.Bool("dry-run", true, "only list the pods that would be deleted, pass -dry-run=false to delete them")
+	fs.Parse(args)
+
+	// deleting every load generator pod of every session needs at least one filter
+	if !*dryRun && *olderThan == 0 && *selector == "" && *prefix == "" {
+		fmt.Println("-dry-run=false requires at least one of -older-than, -selector or -prefix")
+		os.Exit(1)
+	}
+
+	restCfg, err := kubeutils.BuildConfigWithContextFromFlags(*kubeCtx, *kubeconfig)
+	if err != nil {
+		fmt.Println("error creating Kubernetes client configuration:", err)
+		os.Exit(1)
+	}
+
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		fmt.Println("error creating Kubernetes client:", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	pods, err := kubeutils.ListOrchestratorPods(ctx, clientset, *selector)
+	if err != nil {
+		fmt.Println("failed to list pods:", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tPREFIX\tOWNER\tPHASE\tAGE\tACTION")
+
+	failed := 0
+	for _, pod := range pods {
+		if *prefix != "" && pod.Prefix != *prefix {
+			continue
+		}
+
+		owner := pod.Owner
+		if owner == "" {
+			owner = "<none>"
+		}
+
+		action := "keep"
+		if pod.Age >= *olderThan {
+			action = "delete"
+			if *dryRun {
+				action = "delete (dry run)"
+			} else if err := kubeutils.DeleteOrchestratorPod(ctx, clientset, pod); err != nil {
+				action = "failed: " + err.Error()
+				failed++
+			} else {
+				action = "deleted"
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			pod.Namespace, pod.Name, pod.Prefix, owner, pod.Phase, duration.HumanDuration(pod.Age), action)
+	}
+	w.Flush()
+
+	if *dryRun {
+		fmt.Println("\nNothing was deleted, run again with -dry-run=false to delete the pods marked above")
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,274 @@
+package kubeutils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// uploaded artifacts are staged here before they are verified and unpacked
+const artifactStagingDir = workDir + "/.cache"
+
+// jdkDir is where an uploaded JDK archive is unpacked
+const jdkDir = workDir + "/jdk"
+
+// artifactCache holds the local files pushed into pods when they cannot download them on their own
+type artifactCache struct {
+	jmeter cachedArtifact
+	// pluginTars are JMeter Plugins packages, repacked from zip, unpacked into JMeter home
+	pluginTars []cachedArtifact
+	// jars are copied into lib/ext as is
+	jars []cachedArtifact
+	// jdk replaces the JDK installed with apt when set
+	jdk *cachedArtifact
+}
+
+// plugin zips are repacked as tar here, pods have tar but not necessarily unzip or the jar tool
+var repackDir = filepath.Join(os.TempDir(), "jmeter-artifacts")
+
+type cachedArtifact struct {
+	localPath string
+	sha512    string
+}
+
+func (a cachedArtifact) name() string {
+	return filepath.Base(a.localPath)
+}
+
+func (a cachedArtifact) stagingPath() string {
+	return artifactStagingDir + "/" + a.name()
+}
+
+// loadArtifactCache finds everything the installation needs in dir:
+//
+//	apache-jmeter-<version>.tgz
+//	<plugin id>-<version>.zip for Plugins Manager plugins, as published on jmeter-plugins.org
+//	<name>.jar or <name>.zip for plugins given by URL
+//	jdkArchive, a .tar.gz of a JDK, if set. Without it the image has to bring java.
+//
+// Checksums are taken from <file>.sha512 next to a file when present and computed otherwise.
+func loadArtifactCache(dir, version string, plugins []JMeterPlugin, jdkArchive string) (*artifactCache, error) {
+	jmeter, err := loadCachedArtifact(dir, jmeterDistName(version)+".tgz")
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &artifactCache{jmeter: jmeter}
+	for _, plugin := range plugins {
+		if plugin.URL != "" && !plugin.isZip() {
+			jar, err := loadCachedArtifact(dir, path.Base(plugin.URL))
+			if err != nil {
+				return nil, err
+			}
+			cache.jars = append(cache.jars, jar)
+			continue
+		}
+
+		name := path.Base(plugin.URL)
+		if plugin.URL == "" {
+			if plugin.Version == "" {
+				return nil, fmt.Errorf("plugin %s needs a version to be found in the artifact cache", plugin.ID)
+			}
+			name = plugin.ID + "-" + plugin.Version + ".zip"
+		}
+
+		zip, err := loadCachedArtifact(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		repacked, err := repackZipAsTar(zip.localPath)
+		if err != nil {
+			return nil, fmt.Errorf("artifact cache: %w", err)
+		}
+		cache.pluginTars = append(cache.pluginTars, repacked)
+	}
+
+	if jdkArchive != "" {
+		jdk, err := loadCachedArtifact(dir, jdkArchive)
+		if err != nil {
+			return nil, err
+		}
+		cache.jdk = &jdk
+	}
+
+	return cache, nil
+}
+
+// repackZipAsTar writes the entries of a verified zip into a tar in repackDir
+func repackZipAsTar(zipPath string) (cachedArtifact, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return cachedArtifact{}, err
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(repackDir, 0o755); err != nil {
+		return cachedArtifact{}, err
+	}
+	tarPath := filepath.Join(repackDir, strings.TrimSuffix(filepath.Base(zipPath), ".zip")+".tar")
+	out, err := os.Create(tarPath)
+	if err != nil {
+		return cachedArtifact{}, err
+	}
+	defer out.Close()
+
+	tw := tar.NewWriter(out)
+	for _, f := range r.File {
+		if strings.Contains(f.Name, "..") {
+			return cachedArtifact{}, fmt.Errorf("%s: unsafe path %s", zipPath, f.Name)
+		}
+
+		hdr, err := tar.FileInfoHeader(f.FileInfo(), "")
+		if err != nil {
+			return cachedArtifact{}, err
+		}
+		hdr.Name = f.Name
+		if err := tw.WriteHeader(hdr); err != nil {
+			return cachedArtifact{}, err
+		}
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return cachedArtifact{}, err
+		}
+		_, err = io.Copy(tw, rc)
+		rc.Close()
+		if err != nil {
+			return cachedArtifact{}, fmt.Errorf("%s: %w", zipPath, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return cachedArtifact{}, err
+	}
+	if err := out.Close(); err != nil {
+		return cachedArtifact{}, err
+	}
+
+	sum, err := sha512File(tarPath)
+	if err != nil {
+		return cachedArtifact{}, err
+	}
+	return cachedArtifact{localPath: tarPath, sha512: sum}, nil
+}
+
+func loadCachedArtifact(dir, name string) (cachedArtifact, error) {
+	localPath := filepath.Join(dir, name)
+	sum, err := sha512File(localPath)
+	if err != nil {
+		return cachedArtifact{}, fmt.Errorf("artifact cache: %w", err)
+	}
+
+	expected, err := readChecksumFile(localPath + ".sha512")
+	if err != nil {
+		return cachedArtifact{}, fmt.Errorf("artifact cache: %w", err)
+	}
+	if expected != "" && expected != sum {
+		return cachedArtifact{}, fmt.Errorf("artifact cache: %s does not match its SHA-512 checksum", localPath)
+	}
+
+	return cachedArtifact{localPath: localPath, sha512: sum}, nil
+}
+
+func sha512File(localPath string) (string, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha512.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// readChecksumFile reads the first field of a sha512sum style file, "" when there is no such file
+func readChecksumFile(checksumPath string) (string, error) {
+	file, err := os.Open(checksumPath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s is empty", checksumPath)
+}
+
+// getVerifyArtifactCommand fails unless the staged artifact matches the checksum of the local file
+func getVerifyArtifactCommand(artifact cachedArtifact) string {
+	return fmt.Sprintf("echo '%s  %s' | sha512sum -c -", artifact.sha512, artifact.stagingPath())
+}
+
+// getCachedJMeterInstallCommands installs JMeter and plugins from uploaded artifacts, nothing is downloaded by the pod
+func getCachedJMeterInstallCommands(install jmeterInstall) []remoteCommand {
+	cache := install.cache
+	var cmds []remoteCommand
+
+	if cache.jdk != nil {
+		cmds = append(cmds, remoteCommand{
+			displayName: "uploading and installing JDK",
+			upload:      &fileTransfer{localPath: cache.jdk.localPath, remotePath: cache.jdk.stagingPath()},
+			command: fmt.Sprintf("%s && mkdir -p %s && tar -xzf %s -C %s --strip-components=1 && rm %s && "+
+				"ln -sf %s/bin/* /usr/local/bin/ && java -version",
+				getVerifyArtifactCommand(*cache.jdk), jdkDir, cache.jdk.stagingPath(), jdkDir, cache.jdk.stagingPath(), jdkDir),
+		})
+	} else {
+		// apt needs egress, without a JDK archive the image has to bring java
+		cmds = append(cmds, remoteCommand{
+			displayName: "checking java of the image",
+			command:     "java -version",
+		})
+	}
+
+	cmds = append(cmds, remoteCommand{
+		displayName: "uploading and unarchiving JMeter " + install.version,
+		upload:      &fileTransfer{localPath: cache.jmeter.localPath, remotePath: cache.jmeter.stagingPath()},
+		command: fmt.Sprintf("%s && tar -xf %s -C %s && rm %s",
+			getVerifyArtifactCommand(cache.jmeter), cache.jmeter.stagingPath(), workDir, cache.jmeter.stagingPath()),
+	})
+
+	for _, plugin := range cache.pluginTars {
+		cmds = append(cmds, remoteCommand{
+			displayName: "uploading and unpacking plugin " + plugin.name(),
+			upload:      &fileTransfer{localPath: plugin.localPath, remotePath: plugin.stagingPath()},
+			command: fmt.Sprintf("%s && tar -xf %s -C %s && rm %s",
+				getVerifyArtifactCommand(plugin), plugin.stagingPath(), install.home, plugin.stagingPath()),
+		})
+	}
+
+	for _, jar := range cache.jars {
+		cmds = append(cmds, remoteCommand{
+			displayName: "uploading plugin " + jar.name(),
+			upload:      &fileTransfer{localPath: jar.localPath, remotePath: jar.stagingPath()},
+			command: fmt.Sprintf("%s && mv %s %s/lib/ext/",
+				getVerifyArtifactCommand(jar), jar.stagingPath(), install.home),
+		})
+	}
+
+	cmds = append(cmds, remoteCommand{
+		displayName: "testing JMeter installation",
+		command:     install.bin("jmeter") + " --help",
+	})
+
+	return cmds
+}
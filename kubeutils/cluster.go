@@ -3,47 +3,73 @@ package kubeutils
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
-	"os"
-	"strings"
+	"path"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
-func (c *PodsCache) TryGet(ctx context.Context, logger slog.Logger, podName, namespace string, clientset *kubernetes.Clientset) (*v1.Pod, error) {
-	if c.Pods[podName] != nil {
-		return c.Pods[podName], nil
-	}
+var _ Backend = (*Cluster)(nil)
 
-	logger.Info("pod cache missed. initializing cache value", slog.Any("pod", podName))
+// get returns the cached pod, nil on a miss
+func (c *PodsCache) get(podName string) *v1.Pod {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.Pods[podName]
+}
 
+// TryGet returns the cached pod or fetches it. Workers call it concurrently, the API request
+// is made without holding the lock so misses of different pods do not wait for each other.
+func (c *PodsCache) TryGet(ctx context.Context, logger slog.Logger, podName, namespace string, clientset kubernetes.Interface) (*v1.Pod, error) {
+	if pod := c.get(podName); pod != nil {
+		return pod, nil
+	}
+
+	logger.Info("pod cache missed. initializing cache value", slog.Any("pod", podName))
 	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, v1meta.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		// pods created by Jobs have generated names
+		pod, err = findPodByLabel(ctx, clientset, namespace, podName)
+	}
 
 	if err != nil {
 		return nil, err
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.Pods[podName] = pod
-	return pod, err
+	return pod, nil
 }
 
 func (c *Cluster) Ping(ctx context.Context) (bool, error) {
 	connected, err := checkClusterConnection(ctx, c.Clientset)
 	if err != nil {
 		c.Logger.Error(err.Error())
+		return connected, err
 	}
+
+	if c.podTemplate != nil {
+		probe := getPodObject(c.Namespace, c.PodPrefix+"-0", c.Image, c.PodKeepAliveSec, c.podTemplate)
+		err = dryRunPod(ctx, c.Clientset, probe)
+		if err != nil {
+			c.Logger.Error(err.Error())
+			return false, err
+		}
+	}
+
 	return connected, err
 }
 
 func (c *Cluster) CreatePod(ctx context.Context, podName string) error {
-	pod, err := createPod(ctx, c.Clientset, c.Namespace, podName, c.PodKeepAliveSec)
+	pod, err := c.createLoadGenerator(ctx, podName)
 	if err != nil {
 		c.Logger.Error("failed to create pod: ", slog.Any("err", err))
 		return err
@@ -57,9 +83,15 @@ func (c *Cluster) CreatePod(ctx context.Context, podName string) error {
 
 func (c *Cluster) PreparePod(ctx context.Context, testInfo TestInfo, ch chan<- ActionDone) error {
 	podCreationStart := time.Now()
-	pod, err := createPod(ctx, c.Clientset, c.Namespace, testInfo.PodName, c.PodKeepAliveSec)
+	pod, err := c.createLoadGenerator(ctx, testInfo.PodName)
 	if err != nil {
 		c.Logger.Error("failed to create pod: ", slog.Any("err", err.Error()))
+		ch <- ActionDone{
+			PodName:  testInfo.PodName,
+			Name:     "creating pod",
+			Duration: time.Since(podCreationStart),
+			Err:      err,
+		}
 		return err
 	}
 
@@ -71,7 +103,10 @@ func (c *Cluster) PreparePod(ctx context.Context, testInfo TestInfo, ch chan<- A
 
 	podCheckStart := time.Now()
 	testCmd := "pwd"
-	_, _, err = executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, testCmd)
+	attempts, err := c.Retry.retry(ctx, c.reportRetry(ch, testInfo.PodName, "sending a test command to the pod", podCheckStart), func() error {
+		_, _, err := executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, testCmd)
+		return err
+	})
 
 	if err != nil {
 		c.Logger.Error(err.Error())
@@ -81,13 +116,35 @@ func (c *Cluster) PreparePod(ctx context.Context, testInfo TestInfo, ch chan<- A
 		PodName:  testInfo.PodName,
 		Name:     "sending a test command to the pod",
 		Duration: time.Since(podCheckStart),
+		Attempts: attempts,
 	}
 
-	for _, cmd := range getPodSetupCommands() {
+	for _, cmd := range getPodSetupCommands(c.jmeter) {
 		start := time.Now()
-		strBuf, errBuf, err := executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, cmd.command)
+		var transferred int64
+		var strBuf, errBuf string
+		attempts, err := c.Retry.forStep(cmd).retry(ctx, c.reportRetry(ch, testInfo.PodName, cmd.displayName, start), func() error {
+			if cmd.upload != nil {
+				var err error
+				transferred, err = c.UploadToPod(ctx, testInfo.PodName, cmd.upload.localPath, cmd.upload.remotePath, ch)
+				if err != nil {
+					return fmt.Errorf("failed to copy %s to pod: %w", cmd.upload.localPath, err)
+				}
+			}
+
+			var err error
+			strBuf, errBuf, err = executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, cmd.command)
+			return err
+		})
 		if err != nil {
 			c.Logger.Error("failed to execute command: ", slog.Any("err", err.Error()))
+			ch <- ActionDone{
+				PodName:  testInfo.PodName,
+				Name:     cmd.displayName,
+				Duration: time.Since(start),
+				Attempts: attempts,
+				Err:      err,
+			}
 			return err
 		}
 
@@ -95,23 +152,29 @@ func (c *Cluster) PreparePod(ctx context.Context, testInfo TestInfo, ch chan<- A
 			PodName:  testInfo.PodName,
 			Name:     cmd.displayName,
 			Duration: time.Since(start),
+			Bytes:    transferred,
+			Attempts: attempts,
 		}
 
 		c.Logger.Info("command strbuff: " + strBuf)
 		c.Logger.Info("command errbuff: " + errBuf)
 	}
 
-	cpCmds := getTestUploadCommands(testInfo, c.Namespace)
+	uploads, cleanup, err := withBackendListener(testInfo, c.listenerURL)
+	if err != nil {
+		c.Logger.Error("failed to prepare scenario: ", slog.Any("err", err.Error()))
+		return err
+	}
+	defer cleanup()
 
-	switchLocalK8sContext(c.KubeCtxName)
-	for _, cpCmd := range cpCmds {
+	for _, upload := range uploads {
 		start := time.Now()
-		cpCmd.command.Stdout = os.Stdout
-		cpCmd.command.Stderr = os.Stderr
-
-		c.Logger.Info("executing cmd: " + cpCmd.command.String())
-
-		err := cpCmd.command.Run()
+		var transferred int64
+		attempts, err := c.Retry.retry(ctx, c.reportRetry(ch, testInfo.PodName, upload.displayName, start), func() error {
+			var err error
+			transferred, err = c.UploadToPod(ctx, testInfo.PodName, upload.localPath, upload.remotePath, ch)
+			return err
+		})
 		if err != nil {
 			c.Logger.Error("failed to copy file to pod: ", slog.Any("err", err.Error()))
 			return err
@@ -119,88 +182,229 @@ func (c *Cluster) PreparePod(ctx context.Context, testInfo TestInfo, ch chan<- A
 
 		ch <- ActionDone{
 			PodName:  testInfo.PodName,
-			Name:     cpCmd.displayName,
+			Name:     upload.displayName,
 			Duration: time.Since(start),
+			Bytes:    transferred,
+			Attempts: attempts,
 		}
 	}
 
+	if c.Distributed && !isControllerPod(testInfo.PodName, c.PodPrefix) {
+		return c.startEngine(ctx, pod, testInfo.PodName, ch)
+	}
+
 	return nil
 }
 
+// reportRetry tells the UI a step failed and is tried again
+func (c *Cluster) reportRetry(ch chan<- ActionDone, podName, step string, start time.Time) func(int, error) {
+	return func(attempt int, err error) {
+		c.Logger.Warn("retrying step", slog.String("pod", podName), slog.String("step", step),
+			slog.Int("attempt", attempt), slog.String("class", ClassifyError(err).String()), slog.Any("err", err.Error()))
+		ch <- ActionDone{
+			PodName:  podName,
+			Name:     step,
+			Duration: time.Since(start),
+			Attempts: attempt,
+			Err:      err,
+			Retrying: true,
+		}
+	}
+}
+
 func (c *Cluster) CheckProgress(ctx context.Context, testInfo TestInfo) (bool, string, error) {
+	c.events.start(ctx, c.Clientset, c.Namespace, c.PodPrefix, c.Logger)
+
+	if !c.Distributed {
+		return c.checkRunProgress(ctx, testInfo)
+	}
+	if !isControllerPod(testInfo.PodName, c.PodPrefix) {
+		return c.checkEngineProgress(ctx, testInfo)
+	}
+
+	// the controller comes first in every sweep, engines then follow its state
+	isFinished, logs, err := c.checkRunProgress(ctx, testInfo)
+	c.controllerRun.set(isFinished, err)
+	return isFinished, logs, err
+}
+
+// checkRunProgress reads the log increment and the run state of a pod running JMeter itself
+func (c *Cluster) checkRunProgress(ctx context.Context, testInfo TestInfo) (bool, string, error) {
 	isFinished := false
 	pod, err := c.PodsCache.TryGet(ctx, c.Logger, testInfo.PodName, c.Namespace, c.Clientset)
 
 	if err != nil {
 		c.Logger.Error(err.Error())
 		isFinished = true
-		err = errors.New("failed to reach pod")
+		err = c.withPodDiagnostics(ctx, testInfo.PodName, errors.New("failed to reach pod"))
 		return isFinished, "", err
 	}
 
-	stdOut, errOut, err := executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, "cat jmeter/jmeter.log")
+	// a transient failure leaves the run in progress, the caller gives up after a few of them
+	stdOut, errOut, err := c.readLogIncrement(ctx, pod, testInfo.PodName, jmeterLogFile, &c.logs, maxLogChunk)
 	if err != nil {
 		c.Logger.Error(err.Error())
 		c.Logger.Error(errOut)
-		isFinished = true
-		err = errors.New("failed to check run state. check pod's health")
+		isFinished = ClassifyError(err) != ErrorTransient
+		err = c.withPodDiagnostics(ctx, testInfo.PodName, errors.New("failed to check run state. check pod's health"))
 		return isFinished, stdOut, err
 	}
 
+	if c.UseJobs {
+		if jobErr := checkJobStatus(ctx, c.Clientset, c.Namespace, testInfo.PodName); jobErr != nil {
+			c.Logger.Error(jobErr.Error())
+			return true, stdOut, c.withPodDiagnostics(ctx, testInfo.PodName, jobErr)
+		}
+	}
+
 	finishedRunIndicator := getCheckSuccessfulFinishCommand()
-	checkJmeterCmd := getCheckJmeterStateCommand()
 
-	jmeterState, errOut, jErr := executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, checkJmeterCmd)
+	runState, errOut, jErr := executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, getCheckRunStateCommand())
 	if jErr != nil {
 		c.Logger.Error(jErr.Error())
 		c.Logger.Error(errOut)
-		isFinished = true
-		err = errors.New("failed to check run state. check pod's health")
+		isFinished = ClassifyError(jErr) != ErrorTransient
+		err = c.withPodDiagnostics(ctx, testInfo.PodName, errors.New("failed to check run state. check pod's health"))
+		return isFinished, stdOut, err
 	}
 
-	c.Logger.Info("jmeter state", slog.String("pod", testInfo.PodName), slog.String("state", jmeterState))
-	c.Logger.Info("jmeter state err output", slog.String("pod", testInfo.PodName), slog.String("errOut", errOut))
+	run, pErr := parseRunState(runState)
+	if pErr != nil {
+		c.Logger.Error(pErr.Error())
+		return true, stdOut, pErr
+	}
+
+	c.Logger.Info("run state", slog.String("pod", testInfo.PodName), slog.String("state", run.String()))
 
-	jmeterState = strings.TrimSuffix(jmeterState, "\n")
-	if jmeterState == "stopped" {
+	switch run.state {
+	case runExited:
 		isFinished = true
+		if run.exitCode != 0 {
+			err = fmt.Errorf("run %s", run)
+			break
+		}
 		_, _, fErr := executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, finishedRunIndicator)
 		if fErr != nil {
 			err = errors.New("run did not produce results")
 		}
+	case runKilled:
+		isFinished = true
+		err = c.withPodDiagnostics(ctx, testInfo.PodName, fmt.Errorf("run %s", run))
+	case runNotStarted:
+		// the wrapper never ran, e.g. setsid or sh failed, the run would look in progress forever
+		if c.runStarts.startingUp(testInfo.PodName, time.Now()) {
+			break
+		}
+		isFinished = true
+		err = c.withPodDiagnostics(ctx, testInfo.PodName, errors.New("run did not start"))
 	}
 
 	return isFinished, stdOut, err
 }
 
 func (c *Cluster) KickstartTestForPod(ctx context.Context, testInfo TestInfo) error {
-	pod, err := c.PodsCache.TryGet(ctx, c.Logger, testInfo.PodName, c.Namespace, c.Clientset)
+	c.events.start(ctx, c.Clientset, c.Namespace, c.PodPrefix, c.Logger)
+
+	var extraArgs []string
+	if c.Distributed {
+		// engines are driven by the controller
+		if !isControllerPod(testInfo.PodName, c.PodPrefix) {
+			return nil
+		}
+		// forget how the previous run ended
+		c.controllerRun.set(false, nil)
 
+		hosts, err := getEngineHosts(ctx, c.Clientset, c.Namespace, c.PodPrefix)
+		if err != nil {
+			return fmt.Errorf("failed to list engines: %w", err)
+		}
+		if len(hosts) == 0 {
+			return errors.New("no running engines found for the controller")
+		}
+		extraArgs = getControllerRunArgs(testInfo, hosts)
+	}
+
+	pod, err := c.PodsCache.TryGet(ctx, c.Logger, testInfo.PodName, c.Namespace, c.Clientset)
 	if err != nil {
 		c.Logger.Error(err.Error())
+		return fmt.Errorf("failed to get pod %s: %w", testInfo.PodName, err)
 	}
 
-	cmd := getPrepareRunTestCommand(testInfo)
+	cmd := getPrepareRunTestCommand(testInfo, c.jmeter, extraArgs...)
 	_, _, err = executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, cmd)
 
-	go executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, getRunTestCommand())
-	return err
+	if c.UseJobs {
+		// the main process of a Job pod starts the run once the trigger file appears
+		if err == nil {
+			_, _, err = executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, getTriggerRunCommand())
+		}
+		if err == nil {
+			c.runStarts.set(testInfo.PodName, time.Now())
+		}
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, errOut, err := executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, getRunTestCommand())
+	if err != nil {
+		c.Logger.Error("failed to start run", slog.String("pod", testInfo.PodName), slog.String("errOut", errOut))
+		return err
+	}
+	c.runStarts.set(testInfo.PodName, time.Now())
+	return nil
+}
+
+func (c *Cluster) TailResults(ctx context.Context, testInfo TestInfo) (string, error) {
+	pod, err := c.PodsCache.TryGet(ctx, c.Logger, testInfo.PodName, c.Namespace, c.Clientset)
+	if err != nil {
+		return "", err
+	}
+
+	lines, errOut, err := c.readLogIncrement(ctx, pod, testInfo.PodName, workDir+"/"+logFileName, &c.results, maxResultsChunk)
+	if err != nil {
+		c.Logger.Error("failed to read results", slog.String("pod", testInfo.PodName), slog.Any("err", err.Error()), slog.String("errOut", errOut))
+	}
+	return lines, err
+}
+
+func (c *Cluster) PodTarget(podName string) string {
+	return c.KubeCtxName + "/" + c.Namespace
+}
+
+func (c *Cluster) GetPodResources(ctx context.Context, podName string) (PodResources, error) {
+	pod, err := c.PodsCache.TryGet(ctx, c.Logger, podName, c.Namespace, c.Clientset)
+	if err != nil {
+		return PodResources{}, err
+	}
+
+	return getPodResources(ctx, c.Clientset.CoreV1().RESTClient(), pod)
 }
 
 func (c *Cluster) CancelRunForPod(ctx context.Context, testInfo TestInfo) error {
+	// stopping the controller stops the engines
+	if c.Distributed && !isControllerPod(testInfo.PodName, c.PodPrefix) {
+		return nil
+	}
+
 	pod, err := c.PodsCache.TryGet(ctx, c.Logger, testInfo.PodName, c.Namespace, c.Clientset)
 
 	if err != nil {
 		c.Logger.Error(err.Error())
 	}
 
-	stdOut, _, err := executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, getStopTestCommand())
+	stdOut, _, err := executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, getStopTestCommand(c.jmeter))
 	c.Logger.Info(stdOut)
 
 	return err
 }
 
 func (c *Cluster) ResetPodForNewRun(ctx context.Context, testInfo TestInfo) error {
+	c.logs.set(testInfo.PodName, 0)
+	c.results.set(testInfo.PodName, 0)
+
 	pod, err := c.PodsCache.TryGet(ctx, c.Logger, testInfo.PodName, c.Namespace, c.Clientset)
 
 	if err != nil {
@@ -219,6 +423,15 @@ func (c *Cluster) ResetPodForNewRun(ctx context.Context, testInfo TestInfo) erro
 }
 
 func (c *Cluster) CollectResultsFromPod(ctx context.Context, testInfo TestInfo, ch chan<- ActionDone) error {
+	if c.Distributed && !isControllerPod(testInfo.PodName, c.PodPrefix) {
+		ch <- ActionDone{
+			PodName: testInfo.PodName,
+			Name:    "engine results are consolidated in " + c.PodPrefix + "-0",
+		}
+		c.finishJob(ctx, testInfo.PodName)
+		return nil
+	}
+
 	pod, err := c.PodsCache.TryGet(ctx, c.Logger, testInfo.PodName, c.Namespace, c.Clientset)
 
 	if err != nil {
@@ -239,14 +452,9 @@ func (c *Cluster) CollectResultsFromPod(ctx context.Context, testInfo TestInfo,
 	}
 
 	downloadStart := time.Now()
-	downloadResultsCmd := getDownloadResultsCommand(testInfo, c.Namespace, c.PodPrefix)
-
-	downloadResultsCmd.command.Stdout = os.Stdout
-	downloadResultsCmd.command.Stderr = os.Stderr
+	download := getDownloadResultsTransfer(testInfo, c.PodPrefix)
 
-	c.Logger.Info("executing cmd: " + downloadResultsCmd.command.String())
-
-	err = downloadResultsCmd.command.Run()
+	transferred, err := c.DownloadFromPod(ctx, testInfo.PodName, download.remotePath, download.localPath, ch)
 	if err != nil {
 		c.Logger.Error("failed to download results from pod: ", slog.Any("err", err.Error()))
 		return err
@@ -254,26 +462,228 @@ func (c *Cluster) CollectResultsFromPod(ctx context.Context, testInfo TestInfo,
 
 	ch <- ActionDone{
 		PodName:  testInfo.PodName,
-		Name:     downloadResultsCmd.displayName,
+		Name:     download.displayName,
 		Duration: time.Since(downloadStart),
+		Bytes:    transferred,
 	}
 
+	c.finishJob(ctx, testInfo.PodName)
 	return err
 }
 
+// finishJob lets the main process of a Job pod exit once its results are safe, so the Job
+// completes and is removed after its TTL instead of running until the keep-alive deadline
+func (c *Cluster) finishJob(ctx context.Context, podName string) {
+	if !c.UseJobs {
+		return
+	}
+
+	pod, err := c.PodsCache.TryGet(ctx, c.Logger, podName, c.Namespace, c.Clientset)
+	if err == nil {
+		_, _, err = executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, getFinishJobCommand())
+	}
+	if err != nil {
+		c.Logger.Error("failed to finish job", slog.String("pod", podName), slog.Any("err", err.Error()))
+	}
+}
+
+func (c *Cluster) ExecInPod(ctx context.Context, podName, command string) (string, string, error) {
+	pod, err := c.PodsCache.TryGet(ctx, c.Logger, podName, c.Namespace, c.Clientset)
+	if err != nil {
+		return "", "", err
+	}
+
+	return executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, command)
+}
+
+func (c *Cluster) UploadToPod(ctx context.Context, podName, localPath, remotePath string, ch chan<- ActionDone) (int64, error) {
+	pod, err := c.PodsCache.TryGet(ctx, c.Logger, podName, c.Namespace, c.Clientset)
+	if err != nil {
+		return 0, err
+	}
+
+	c.Logger.Info("uploading file", slog.String("pod", podName), slog.String("from", localPath), slog.String("to", remotePath))
+
+	_, fileName := path.Split(remotePath)
+	progress := reportTransferProgress(ch, podName, "uploading "+fileName)
+	return uploadFile(ctx, c.RestCfg, c.Clientset, pod, localPath, remotePath, progress)
+}
+
+func (c *Cluster) DownloadFromPod(ctx context.Context, podName, remotePath, localPath string, ch chan<- ActionDone) (int64, error) {
+	pod, err := c.PodsCache.TryGet(ctx, c.Logger, podName, c.Namespace, c.Clientset)
+	if err != nil {
+		return 0, err
+	}
+
+	c.Logger.Info("downloading file", slog.String("pod", podName), slog.String("from", remotePath), slog.String("to", localPath))
+
+	_, fileName := path.Split(remotePath)
+	progress := reportTransferProgress(ch, podName, "downloading "+fileName)
+	return downloadFile(ctx, c.RestCfg, c.Clientset, pod, remotePath, localPath, progress)
+}
+
 func (c *Cluster) DeletePod(ctx context.Context, podName string) error {
-	err := deletePod(ctx, c.Clientset, c.Namespace, podName)
+	var err error
+	if c.UseJobs {
+		err = deleteJob(ctx, c.Clientset, c.Namespace, podName)
+	} else {
+		err = deletePod(ctx, c.Clientset, c.Namespace, podName)
+	}
 	if err != nil {
 		c.Logger.Error("failed to delete pod: ", slog.Any("err", err.Error()))
 		return err
 	}
 
+	return c.deleteSessionServices(ctx, podName)
+}
+
+// deleteSessionServices removes the Services created along with the first pod of the session
+func (c *Cluster) deleteSessionServices(ctx context.Context, podName string) error {
+	if c.Distributed && isControllerPod(podName, c.PodPrefix) {
+		if err := deleteEngineService(ctx, c.Clientset, c.Namespace, c.PodPrefix); err != nil {
+			c.Logger.Error("failed to delete engines service: ", slog.Any("err", err.Error()))
+			return err
+		}
+	}
+
+	if c.ListenerAddr != "" && podName == fmt.Sprintf("%s-%d", c.PodPrefix, c.firstPod) {
+		if err := deleteListenerService(ctx, c.Clientset, c.Namespace, c.PodPrefix); err != nil {
+			c.Logger.Error("failed to delete metrics service: ", slog.Any("err", err.Error()))
+			return err
+		}
+	}
+
 	return nil
 }
 
+func (c *Cluster) RollbackPod(ctx context.Context, podName string) error {
+	var err error
+	if c.UseJobs {
+		err = deleteJob(ctx, c.Clientset, c.Namespace, podName)
+	} else {
+		err = deletePod(ctx, c.Clientset, c.Namespace, podName)
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		c.Logger.Error("failed to roll back pod: ", slog.Any("err", err.Error()))
+		return err
+	}
+
+	if err := c.deleteSessionServices(ctx, podName); err != nil {
+		return err
+	}
+
+	return waitForPodGone(ctx, c.Clientset, c.Namespace, podName, c.ReadyTimeout)
+}
+
+func (c *Cluster) createLoadGenerator(ctx context.Context, podName string) (*v1.Pod, error) {
+	if c.Distributed {
+		if err := ensureEngineService(ctx, c.Clientset, c.Namespace, c.PodPrefix); err != nil {
+			return nil, fmt.Errorf("failed to create engines service: %w", err)
+		}
+	}
+
+	if c.ListenerAddr != "" {
+		if err := ensureListenerService(ctx, c.Clientset, c.Namespace, c.PodPrefix, c.ListenerAddr); err != nil {
+			return nil, fmt.Errorf("failed to create metrics service: %w", err)
+		}
+	}
+
+	template := c.podTemplateFor(podName)
+	if c.UseJobs {
+		return createJob(ctx, c.Clientset, c.Namespace, podName, c.Image, c.PodKeepAliveSec, c.JobTTLSec, template, c.ReadyTimeout)
+	}
+	return createPod(ctx, c.Clientset, c.Namespace, podName, c.Image, c.PodKeepAliveSec, template, c.ReadyTimeout)
+}
+
+func NewCluster(cfg ClusterConfig, logger slog.Logger) (*Cluster, error) {
+	restCfg, err := BuildConfigWithContextFromFlags(cfg.KubeCtxName, cfg.KubeconfigPath)
+	if err != nil {
+		logger.Error("error creating Kubernetes client configuration: ", slog.Any("err", err))
+		return nil, err
+	}
+
+	if cfg.QPS > 0 {
+		restCfg.QPS = cfg.QPS
+	}
+	if cfg.Burst > 0 {
+		restCfg.Burst = cfg.Burst
+	}
+
+	readyTimeout := cfg.ReadyTimeout
+	if readyTimeout <= 0 {
+		readyTimeout = DefaultReadyTimeout
+	}
+
+	jmeter, err := newJMeterInstall(cfg)
+	if err != nil {
+		logger.Error("invalid JMeter installation settings: ", slog.Any("err", err))
+		return nil, err
+	}
+
+	var template *v1.Pod
+	if cfg.PodTemplatePath != "" {
+		template, err = LoadPodTemplate(cfg.PodTemplatePath)
+		if err != nil {
+			logger.Error("error loading pod template: ", slog.Any("err", err))
+			return nil, err
+		}
+	}
+
+	retry := cfg.Retry
+	if retry.Attempts == 0 {
+		retry = DefaultRetryPolicy()
+	}
+
+	var listenerURL string
+	if cfg.ListenerAddr != "" {
+		listenerURL, err = getListenerURL(cfg.ListenerAddr, cfg.Namespace, cfg.PodPrefix)
+		if err != nil {
+			logger.Error("invalid listener address: ", slog.Any("err", err))
+			return nil, err
+		}
+	}
+
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		logger.Error("error creating Kubernetes client: ", slog.Any("err", err))
+		return nil, err
+	}
+
+	cluster := Cluster{
+		RestCfg:         restCfg,
+		Clientset:       clientset,
+		Namespace:       cfg.Namespace,
+		KubeCtxName:     cfg.KubeCtxName,
+		PodPrefix:       cfg.PodPrefix,
+		PodKeepAliveSec: cfg.PodKeepAliveSec,
+		Image:           cfg.Image,
+		UseJobs:         cfg.UseJobs,
+		JobTTLSec:       cfg.JobTTLSec,
+		ReadyTimeout:    readyTimeout,
+		Distributed:     cfg.Distributed,
+		Retry:           retry,
+		ListenerAddr:    cfg.ListenerAddr,
+		PodsCache: &PodsCache{
+			Pods: make(map[string]*v1.Pod),
+		},
+		Logger:      logger,
+		jmeter:      jmeter,
+		podTemplate: template,
+		listenerURL: listenerURL,
+		firstPod:    cfg.FirstPod,
+	}
+	return &cluster, nil
+}
+
+// BuildConfigWithContextFromFlags resolves a rest config for the given context without ever
+// touching the current-context of the kubeconfig. When kubeconfigPath is empty the standard
+// loading rules apply: every file listed in KUBECONFIG is merged, falling back to ~/.kube/config.
 func BuildConfigWithContextFromFlags(context string, kubeconfigPath string) (*rest.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfigPath
+
 	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfigPath},
+		loadingRules,
 		&clientcmd.ConfigOverrides{
 			CurrentContext: context,
 		}).ClientConfig()
@@ -0,0 +1,58 @@
+package kubeutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// pool workers prepare, check and diagnose pods at the same time, run with -race
+func TestPodsCacheConcurrentAccess(t *testing.T) {
+	const pods = 20
+
+	var objects []*v1.Pod
+	for i := range pods {
+		objects = append(objects, &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("load-%d", i), Namespace: "perf"}})
+	}
+	clientset := fake.NewSimpleClientset()
+	for _, pod := range objects {
+		if _, err := clientset.CoreV1().Pods("perf").Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := &Cluster{
+		Clientset: clientset,
+		Namespace: "perf",
+		PodPrefix: "load",
+		PodsCache: &PodsCache{Pods: make(map[string]*v1.Pod)},
+		Logger:    *slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	var wg sync.WaitGroup
+	for i := range pods * 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			podName := fmt.Sprintf("load-%d", i%pods)
+			pod, err := c.PodsCache.TryGet(context.Background(), c.Logger, podName, c.Namespace, c.Clientset)
+			if err != nil || pod.Name != podName {
+				t.Errorf("TryGet(%s) = %v, %v", podName, pod, err)
+			}
+			c.withPodDiagnostics(context.Background(), podName, errors.New("failed"))
+		}()
+	}
+	wg.Wait()
+
+	if len(c.PodsCache.Pods) != pods {
+		t.Errorf("got %d cached pods, want %d", len(c.PodsCache.Pods), pods)
+	}
+}
@@ -4,174 +4,169 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"runtime"
 	"strings"
+	"time"
 )
 
+// DefaultReadyTimeout is how long a pod has to become ready unless configured otherwise
+const DefaultReadyTimeout = 3 * time.Minute
+
+// DefaultPodImage is used when neither -image nor a pod template sets one
+const DefaultPodImage = "ubuntu:22.04"
+
 const logFileName = "newlog.jtl"
+const workDir = "/jmeter"
+const jmeterLogFile = workDir + "/jmeter.log"
+
+// runTriggerFile tells the main process of a Job pod to start run.sh
+const runTriggerFile = workDir + "/.start"
+
+// runFinishFile tells the main process of a Job pod to exit, which completes the Job
+const runFinishFile = workDir + "/.finish"
 const resultsPath = "LoadTestResutls/"
 
+// long file transfers report progress every time this many bytes are moved
+const transferProgressStep = 8 << 20
+
+// a download is aborted when no data arrives from the pod for this long
+const transferStallTimeout = 60 * time.Second
+
 // Pod setup
 const (
 	installAndUpdateDeps = "apt update && apt install openjdk-11-jre-headless wget unzip nano -y"
-	downloadJmeter       = "mkdir jmeter && cd jmeter && wget https://dlcdn.apache.org//jmeter/binaries/apache-jmeter-5.6.3.tgz"
-	unpackJmeterArchive  = "cd jmeter && tar -xf apache-jmeter-5.6.3.tgz && rm apache-jmeter-5.6.3.tgz"
-	downloadPlugin       = "cd jmeter && wget https://jmeter-plugins.org/files/packages/jpgc-casutg-2.10.zip"
-	unpackPluginArchive  = "cd jmeter && unzip jpgc-casutg-2.10.zip -d apache-jmeter-5.6.3/ && rm jpgc-casutg-2.10.zip"
-	testJmeter           = "jmeter/apache-jmeter-5.6.3/bin/jmeter --help"
+	// apt exits non-zero while another apt holds its lock, so it gets more attempts
+	aptAttempts = 6
+)
+
+// Prebuilt image setup
+const (
+	createWorkDir = "mkdir -p " + workDir
 )
 
 // Test reset
 const (
-	removeResultsDir  = "rm -r jmeter/" + resultsPath
-	removeJmeterLog   = "rm jmeter/jmeter.log"
-	removeRequestsLog = "rm jmeter/newlog.jtl"
+	removeRunState    = "rm -f " + runPidFile + " " + runExitFile
+	removeResultsDir  = "rm -r " + workDir + "/" + resultsPath
+	removeJmeterLog   = "rm " + jmeterLogFile
+	removeRequestsLog = "rm " + workDir + "/newlog.jtl"
 )
 
-func getPodSetupCommands() []remoteCommand {
+func getPodSetupCommands(install jmeterInstall) []remoteCommand {
 	var cmds []remoteCommand
 
-	cmds = append(cmds, remoteCommand{
-		displayName: "updating packages and installing jdk",
-		command:     installAndUpdateDeps,
-	})
-
-	cmds = append(cmds, remoteCommand{
-		displayName: "downloading JMeter",
-		command:     downloadJmeter,
-	})
+	if install.prebuilt {
+		cmds = append(cmds, remoteCommand{
+			displayName: "preparing working directory",
+			command:     createWorkDir,
+		})
 
-	cmds = append(cmds, remoteCommand{
-		displayName: "unarchiving JMeter and removing archive",
-		command:     unpackJmeterArchive,
-	})
+		cmds = append(cmds, remoteCommand{
+			displayName: "verifying prebuilt JMeter installation",
+			command:     install.bin("jmeter") + " --version",
+		})
 
-	cmds = append(cmds, remoteCommand{
-		displayName: "downloading threads plugin",
-		command:     downloadPlugin,
-	})
+		return cmds
+	}
 
-	cmds = append(cmds, remoteCommand{
-		displayName: "unpacking plugin and removing archive",
-		command:     unpackPluginArchive,
-	})
+	if install.cache != nil {
+		return getCachedJMeterInstallCommands(install)
+	}
 
 	cmds = append(cmds, remoteCommand{
-		displayName: "testing JMeter installation",
-		command:     testJmeter,
+		displayName:    "updating packages and installing jdk",
+		command:        installAndUpdateDeps,
+		attempts:       aptAttempts,
+		retryExitCodes: true,
 	})
 
-	return cmds
+	return append(cmds, getJMeterInstallCommands(install)...)
 }
 
-func getTestUploadCommands(test TestInfo, namespace string) []localCommand {
-	var cmds []localCommand
+func getTestUploadTransfers(test TestInfo) []fileTransfer {
 	_, scenarioFilePath := filepath.Split(test.ScenarioFileName)
 	_, propertiesFilePath := filepath.Split(test.PropFileName)
 
-	var cpyScenarioCmd *exec.Cmd
-	var cpyProprsCmd *exec.Cmd
-
-	if runtime.GOOS == "windows" {
-		currDir, _ := os.Getwd()
-		relPathToScenario, _ := filepath.Rel(currDir, test.ScenarioFileName)
-		cpyScenarioCmd = exec.Command(
-			"kubectl",
-			"cp",
-			"-n",
-			namespace,
-			relPathToScenario,
-			test.PodName+":/jmeter/"+scenarioFilePath,
-			"-c",
-			test.PodName,
-		)
-
-		relPathToProperties, _ := filepath.Rel(currDir, test.PropFileName)
-		cpyProprsCmd = exec.Command(
-			"kubectl",
-			"cp",
-			"-n",
-			namespace,
-			relPathToProperties,
-			test.PodName+":/jmeter/"+propertiesFilePath,
-			"-c",
-			test.PodName,
-		)
-	} else {
-		cpyScenarioCmd = exec.Command(
-			"kubectl",
-			"cp",
-			"-n",
-			namespace,
-			test.ScenarioFileName,
-			test.PodName+":/jmeter/"+scenarioFilePath,
-			"-c",
-			test.PodName,
-		)
-
-		cpyProprsCmd = exec.Command(
-			"kubectl",
-			"cp",
-			"-n",
-			namespace,
-			test.PropFileName,
-			test.PodName+":/jmeter/"+propertiesFilePath,
-			"-c",
-			test.PodName,
-		)
-	}
-
-	uploadScenario := localCommand{
+	uploadScenario := fileTransfer{
 		displayName: "upload scenario file",
-		command:     cpyScenarioCmd,
+		localPath:   test.ScenarioFileName,
+		remotePath:  "/jmeter/" + scenarioFilePath,
 	}
 
-	uploadProperties := localCommand{
+	uploadProperties := fileTransfer{
 		displayName: "upload properties file",
-		command:     cpyProprsCmd,
+		localPath:   test.PropFileName,
+		remotePath:  "/jmeter/" + propertiesFilePath,
 	}
 
-	cmds = append(cmds, uploadScenario, uploadProperties)
-	return cmds
+	return []fileTransfer{uploadScenario, uploadProperties}
+}
+
+func getUnpackUploadCommand(remoteDir string) string {
+	return fmt.Sprintf("mkdir -p '%s' && tar -xmf - -C '%s'", remoteDir, remoteDir)
+}
+
+func getFileInfoCommand(remotePath string) string {
+	return fmt.Sprintf("stat -c %%s '%s' && sha256sum '%s'", remotePath, remotePath)
+}
+
+func getStreamFileCommand(remotePath string) string {
+	return fmt.Sprintf("cat '%s'", remotePath)
 }
 
-func getPrepareRunTestCommand(test TestInfo) string {
+// getPrepareRunTestCommand writes run.sh, extraArgs are appended to the jmeter command line
+func getPrepareRunTestCommand(test TestInfo, install jmeterInstall, extraArgs ...string) string {
+	args := ""
+	if len(extraArgs) > 0 {
+		args = " " + strings.Join(extraArgs, " ")
+	}
+
 	copyScenario := fmt.Sprintf(
-		"touch jmeter/run.sh &&"+
-			"echo \"apache-jmeter-5.6.3/bin/jmeter -q %s -n -t '%s' -e -o %s -l %s\" > jmeter/run.sh &&"+
-			"chmod +x jmeter/run.sh",
+		"touch /jmeter/run.sh &&"+
+			"echo \"%s -q %s -n -t '%s' -e -o %s -l %s%s\" > /jmeter/run.sh &&"+
+			"chmod +x /jmeter/run.sh",
+		install.bin("jmeter"),
 		test.PropFileName,
 		test.ScenarioFileName,
 		resultsPath,
-		logFileName)
+		logFileName,
+		args)
 	return copyScenario
 }
 
+func getTriggerRunCommand() string {
+	return removeRunState + " && touch " + runTriggerFile
+}
+
+func getFinishJobCommand() string {
+	return "touch " + runFinishFile
+}
+
+// getRunTestCommand starts the run detached from the exec session, so it outlives the orchestrator
 func getRunTestCommand() string {
-	runTestCmd := "cd jmeter && sh ./run.sh &"
+	runTestCmd := fmt.Sprintf("cd /jmeter && %s && setsid nohup sh -c '%s' > /dev/null 2>&1 < /dev/null &",
+		removeRunState, getSupervisedRunScript())
 	return runTestCmd
 }
 
-func getStopTestCommand() string {
-	stopCmd := "sh jmeter/apache-jmeter-5.6.3/bin/stoptest.sh"
+func getStopTestCommand(install jmeterInstall) string {
+	stopCmd := "sh " + install.bin("stoptest.sh")
 	return stopCmd
 }
 
 func getResetTestCommands() []string {
-	resetCmds := []string{removeResultsDir, removeJmeterLog, removeRequestsLog}
+	resetCmds := []string{removeRunState, removeResultsDir, removeJmeterLog, removeRequestsLog}
 
 	return resetCmds
 }
 
 func getPackResultsCommand() string {
 	resultFolderName := strings.TrimSuffix(resultsPath, "/")
-	packResultsCmd := fmt.Sprintf("tar -zcvf jmeter/%s.tar.gz /jmeter/%s", resultFolderName, resultFolderName)
+	packResultsCmd := fmt.Sprintf("tar -zcvf /jmeter/%s.tar.gz /jmeter/%s", resultFolderName, resultFolderName)
 	return packResultsCmd
 }
 
-func getDownloadResultsCommand(test TestInfo, namespace string, podPrefix string) localCommand {
+func getDownloadResultsTransfer(test TestInfo, podPrefix string) fileTransfer {
 	ext := ".tar.gz"
 	podResultsName := strings.TrimSuffix(resultsPath, "/")
 	archivePath := podResultsName + ext
@@ -181,31 +176,14 @@ func getDownloadResultsCommand(test TestInfo, namespace string, podPrefix string
 
 	localResultFilePath := pathToDir + podResultsName + ext
 
-	cpyProprsCmd := exec.Command(
-		"kubectl",
-		"cp",
-		"-n",
-		namespace,
-		test.PodName+":/jmeter/"+archivePath,
-		localResultFilePath,
-		"-c",
-		test.PodName,
-	)
-
-	cmd := localCommand{
+	return fileTransfer{
 		displayName: "results saved to " + localResultFilePath,
-		command:     cpyProprsCmd,
+		localPath:   localResultFilePath,
+		remotePath:  "/jmeter/" + archivePath,
 	}
-
-	return cmd
 }
 
 func getCheckSuccessfulFinishCommand() string {
-	finishedRunIndicator := "cd jmeter/" + resultsPath
+	finishedRunIndicator := "cd /jmeter/" + resultsPath
 	return finishedRunIndicator
 }
-
-func getCheckJmeterStateCommand() string {
-	checkJmeterCmd := "top -bn1 | grep jmeter && echo 'running' || echo 'stopped'"
-	return checkJmeterCmd
-}
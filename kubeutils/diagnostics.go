@@ -0,0 +1,208 @@
+package kubeutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// only the latest few events are kept for every pod
+const maxEventsPerPod = 3
+
+// normal events worth reporting, warnings are always kept
+var notableEventReasons = []string{"Killing", "Preempted", "Evicted", "NodeNotReady"}
+
+// podEvents keeps the latest notable events of the orchestrator pods, fed by a watch on namespace events
+type podEvents struct {
+	mu    sync.Mutex
+	byPod map[string][]string
+	once  sync.Once
+}
+
+func (e *podEvents) start(ctx context.Context, clientset kubernetes.Interface, namespace, podPrefix string, logger slog.Logger) {
+	e.once.Do(func() {
+		e.byPod = make(map[string][]string)
+		go e.watch(ctx, clientset, namespace, podPrefix, logger)
+	})
+}
+
+const podEventsSelector = "involvedObject.kind=Pod"
+
+// watch follows pod events until ctx is done. A watch that cannot be resumed is started over
+// from the current state with a growing delay, so a failing API server is not hammered.
+func (e *podEvents) watch(ctx context.Context, clientset kubernetes.Interface, namespace, podPrefix string, logger slog.Logger) {
+	backoff := wait.Backoff{Duration: time.Second, Factor: 2, Jitter: 0.5, Steps: 8, Cap: time.Minute}
+	for ctx.Err() == nil {
+		started := time.Now()
+		err := e.watchFromNow(ctx, clientset, namespace, podPrefix, logger)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Error("failed to watch pod events", slog.Any("err", err.Error()))
+		}
+
+		// a watch that ran for a while was healthy, the next failure starts over with a short delay
+		if time.Since(started) > backoff.Cap {
+			backoff = wait.Backoff{Duration: time.Second, Factor: 2, Jitter: 0.5, Steps: 8, Cap: time.Minute}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff.Step()):
+		}
+	}
+}
+
+// watchFromNow lists events to learn the current resource version and watches from there,
+// so events of earlier pods that had the same names are not picked up
+func (e *podEvents) watchFromNow(ctx context.Context, clientset kubernetes.Interface, namespace, podPrefix string, logger slog.Logger) error {
+	list, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: podEventsSelector,
+		Limit:         1,
+	})
+	if err != nil {
+		return err
+	}
+
+	w, err := watchtools.NewRetryWatcher(list.ResourceVersion, &cache.ListWatch{
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = podEventsSelector
+			return clientset.CoreV1().Events(namespace).Watch(ctx, options)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+
+	for {
+		var ev watch.Event
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok = <-w.ResultChan():
+		}
+		if !ok {
+			return errors.New("event watch closed")
+		}
+		if ev.Type == watch.Error {
+			return apierrors.FromObject(ev.Object)
+		}
+
+		event, isEvent := ev.Object.(*v1.Event)
+		if !isEvent || !isSessionPodOrJobPod(event.InvolvedObject.Name, podPrefix) {
+			continue
+		}
+
+		if event.Type != v1.EventTypeWarning && !slices.Contains(notableEventReasons, event.Reason) {
+			continue
+		}
+
+		logger.Info("pod event", slog.String("pod", event.InvolvedObject.Name), slog.String("reason", event.Reason), slog.String("msg", event.Message))
+		e.add(event.InvolvedObject.Name, event.Reason+": "+event.Message)
+	}
+}
+
+// isSessionPodOrJobPod also matches pods of Jobs, named after their Job with a random suffix
+func isSessionPodOrJobPod(podName, prefix string) bool {
+	if isSessionPod(podName, prefix) {
+		return true
+	}
+	idx := strings.LastIndex(podName, "-")
+	return idx > 0 && isSessionPod(podName[:idx], prefix)
+}
+
+func (e *podEvents) add(podName, event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	events := e.byPod[podName]
+	if slices.Contains(events, event) {
+		return
+	}
+	events = append(events, event)
+	if len(events) > maxEventsPerPod {
+		events = events[len(events)-maxEventsPerPod:]
+	}
+	e.byPod[podName] = events
+}
+
+func (e *podEvents) get(podName string) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.byPod[podName])
+}
+
+// getTerminationReasons collects why a pod or its containers stopped: eviction, preemption, OOM kills and so on
+func getTerminationReasons(pod *v1.Pod) []string {
+	var reasons []string
+
+	if pod.Status.Reason != "" {
+		reasons = append(reasons, strings.TrimSpace(pod.Status.Reason+": "+pod.Status.Message))
+	}
+
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.DisruptionTarget && cond.Status == v1.ConditionTrue {
+			reasons = append(reasons, cond.Reason+": "+cond.Message)
+		}
+	}
+
+	for _, cs := range pod.Status.ContainerStatuses {
+		terminated := cs.State.Terminated
+		if terminated == nil {
+			terminated = cs.LastTerminationState.Terminated
+		}
+		if terminated == nil {
+			continue
+		}
+
+		reason := fmt.Sprintf("%s (exit code %d)", terminated.Reason, terminated.ExitCode)
+		if terminated.Message != "" {
+			reason += ": " + terminated.Message
+		}
+		reasons = append(reasons, reason)
+	}
+
+	return reasons
+}
+
+// withPodDiagnostics appends termination reasons and recent events of the pod to err
+func (c *Cluster) withPodDiagnostics(ctx context.Context, podName string, err error) error {
+	actualName := podName
+	if cached := c.PodsCache.get(podName); cached != nil {
+		actualName = cached.Name
+	}
+
+	var details []string
+	pod, getErr := c.Clientset.CoreV1().Pods(c.Namespace).Get(ctx, actualName, metav1.GetOptions{})
+	switch {
+	case getErr == nil:
+		details = append(details, getTerminationReasons(pod)...)
+	case apierrors.IsNotFound(getErr):
+		details = append(details, "pod no longer exists")
+	}
+
+	details = append(details, c.events.get(actualName)...)
+	if len(details) == 0 {
+		return err
+	}
+
+	return fmt.Errorf("%w: %s", err, strings.Join(details, "; "))
+}
@@ -0,0 +1,138 @@
+package kubeutils
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RunStatus is what discovery found out about the last run in a pod
+type RunStatus uint
+
+const (
+	RunNotStarted RunStatus = iota
+	RunInProgress
+	RunFinished
+	// RunUnknown means the pod could not be inspected
+	RunUnknown
+)
+
+// DiscoveredPod describes a load generator pod left over from an earlier session
+type DiscoveredPod struct {
+	Name             string
+	JMeterInstalled  bool
+	ScenarioFileName string
+	PropFileName     string
+	Run              RunStatus
+	// Err is why the pod could not be inspected, the other fields are unknown then
+	Err error
+}
+
+// DiscoverPods finds running pods named <prefix>-N carrying the orchestrator label and inspects
+// what has already been done in them: JMeter installation, uploaded files and the state of a run.
+// A pod that fails to be inspected is returned with Err set, so the other pods can still be reattached to.
+func (c *Cluster) DiscoverPods(ctx context.Context) ([]DiscoveredPod, error) {
+	pods, err := c.Clientset.CoreV1().Pods(c.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: appLabelKey + "=" + appLabelValue,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var discovered []DiscoveredPod
+	for _, pod := range pods.Items {
+		name := loadGeneratorContainerName(&pod)
+		if !isSessionPod(name, c.PodPrefix) || pod.DeletionTimestamp != nil || pod.Status.Phase != v1.PodRunning {
+			continue
+		}
+
+		out, errOut, err := executeRemoteCommand(ctx, c.RestCfg, c.Clientset, &pod, getInspectPodCommand(c.jmeter))
+		if err != nil {
+			c.Logger.Error("failed to inspect pod", slog.String("pod", name), slog.Any("err", err.Error()), slog.String("errOut", errOut))
+			discovered = append(discovered, DiscoveredPod{
+				Name: name,
+				Run:  RunUnknown,
+				Err:  fmt.Errorf("failed to inspect pod: %w", err),
+			})
+			continue
+		}
+
+		d := parseInspectOutput(out)
+		d.Name = name
+		discovered = append(discovered, d)
+
+		c.Logger.Info("discovered pod", slog.String("pod", name), slog.Any("state", d))
+	}
+
+	sortByPodIndex(discovered)
+	return discovered, nil
+}
+
+// isSessionPod tells if podName is <prefix>-N. It runs for every event of the namespace, so no regexp.
+func isSessionPod(podName, prefix string) bool {
+	index, ok := strings.CutPrefix(podName, prefix+"-")
+	if !ok || index == "" {
+		return false
+	}
+	for i := 0; i < len(index); i++ {
+		if index[i] < '0' || index[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func sortByPodIndex(pods []DiscoveredPod) {
+	index := func(name string) int {
+		n, _ := strconv.Atoi(name[strings.LastIndex(name, "-")+1:])
+		return n
+	}
+
+	sort.Slice(pods, func(i, j int) bool {
+		return index(pods[i].Name) < index(pods[j].Name)
+	})
+}
+
+func getInspectPodCommand(install jmeterInstall) string {
+	return fmt.Sprintf(
+		"cd %s 2>/dev/null || exit 0; "+
+			"[ -x %s ] && echo installed; "+
+			"for f in *.jmx; do [ -f \"$f\" ] && echo \"scenario=$f\"; done; "+
+			"for f in *.properties; do [ -f \"$f\" ] && echo \"properties=$f\"; done; "+
+			"(%s) | grep -qx running && echo running; "+
+			"[ -d %s ] && echo results; "+
+			"exit 0",
+		workDir, install.bin("jmeter"), getCheckRunStateCommand(), resultsPath)
+}
+
+func parseInspectOutput(out string) DiscoveredPod {
+	var d DiscoveredPod
+	for _, line := range strings.Split(out, "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+		switch key {
+		case "installed":
+			d.JMeterInstalled = true
+		case "scenario":
+			if d.ScenarioFileName == "" {
+				d.ScenarioFileName = value
+			}
+		case "properties":
+			if d.PropFileName == "" {
+				d.PropFileName = value
+			}
+		case "running":
+			d.Run = RunInProgress
+		case "results":
+			if d.Run != RunInProgress {
+				d.Run = RunFinished
+			}
+		}
+	}
+	return d
+}
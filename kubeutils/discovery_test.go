@@ -0,0 +1,70 @@
+package kubeutils
+
+import "testing"
+
+func TestIsSessionPod(t *testing.T) {
+	tests := []struct {
+		podName string
+		session bool
+		job     bool
+	}{
+		{podName: "load-0", session: true, job: true},
+		{podName: "load-42", session: true, job: true},
+		{podName: "load-", session: false},
+		{podName: "load", session: false},
+		{podName: "load-a", session: false},
+		{podName: "load-+1", session: false},
+		{podName: "load-1-2", session: false, job: true},
+		{podName: "loadtest-1", session: false},
+		{podName: "my-load-1", session: false},
+		{podName: "load-3-x7k2p", session: false, job: true},
+		{podName: "load-metrics-x7k2p", session: false},
+	}
+
+	for _, tt := range tests {
+		if got := isSessionPod(tt.podName, "load"); got != tt.session {
+			t.Errorf("isSessionPod(%q) = %v, want %v", tt.podName, got, tt.session)
+		}
+		if got := isSessionPodOrJobPod(tt.podName, "load"); got != tt.job {
+			t.Errorf("isSessionPodOrJobPod(%q) = %v, want %v", tt.podName, got, tt.job)
+		}
+	}
+
+	// prefixes may contain regexp metacharacters
+	if !isSessionPod("load.v2-1", "load.v2") || isSessionPod("loadxv2-1", "load.v2") {
+		t.Error("prefix is not matched literally")
+	}
+}
+
+func TestParseInspectOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want DiscoveredPod
+	}{
+		{name: "empty pod", out: ""},
+		{
+			name: "prepared",
+			out:  "installed\nscenario=test.jmx\nproperties=test.properties\n",
+			want: DiscoveredPod{JMeterInstalled: true, ScenarioFileName: "test.jmx", PropFileName: "test.properties"},
+		},
+		{
+			name: "running with results of an earlier run",
+			out:  "installed\nscenario=a.jmx\nscenario=b.jmx\nproperties=p.properties\nrunning\nresults\n",
+			want: DiscoveredPod{JMeterInstalled: true, ScenarioFileName: "a.jmx", PropFileName: "p.properties", Run: RunInProgress},
+		},
+		{
+			name: "finished",
+			out:  "installed\nscenario=a.jmx\nproperties=p.properties\nresults\n",
+			want: DiscoveredPod{JMeterInstalled: true, ScenarioFileName: "a.jmx", PropFileName: "p.properties", Run: RunFinished},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseInspectOutput(tt.out); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
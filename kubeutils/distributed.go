@@ -0,0 +1,240 @@
+package kubeutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+// In distributed mode <prefix>-0 is the controller and every other pod runs jmeter-server
+const (
+	roleLabel      = "jmeter_role"
+	roleController = "controller"
+	roleEngine     = "engine"
+	// engineReadyLabel is set once an engine is prepared and jmeter-server runs, engines
+	// that failed to prepare stay out of the controller's host list
+	engineReadyLabel = "jmeter_engine_ready"
+)
+
+// RMI ports are fixed so they can be declared on the engines Service. SSL for RMI is disabled,
+// the traffic never leaves the cluster network.
+const (
+	rmiRegistryPort  = 1099
+	serverRMIPort    = 50000
+	clientRMIPort    = 60000
+	rmiSSLDisabled   = "-Jserver.rmi.ssl.disable=true"
+	rmiHostnameToPod = "-Djava.rmi.server.hostname=$(hostname -i | cut -d' ' -f1)"
+)
+
+// jmeter-server writes its log next to where it was started
+const engineLogFile = workDir + "/jmeter-server.log"
+const engineOutFile = workDir + "/jmeter-server.out"
+
+func isControllerPod(podName, prefix string) bool {
+	return podName == prefix+"-0"
+}
+
+func engineServiceName(prefix string) string {
+	return prefix + "-engines"
+}
+
+// podTemplateFor returns the template of a pod with its distributed role applied. Engines get
+// a hostname under the headless engines Service, so the controller can reach them by name.
+func (c *Cluster) podTemplateFor(podName string) *v1.Pod {
+	if !c.Distributed {
+		return c.podTemplate
+	}
+
+	template := &v1.Pod{}
+	if c.podTemplate != nil {
+		template = c.podTemplate.DeepCopy()
+	}
+	if template.Labels == nil {
+		template.Labels = make(map[string]string)
+	}
+
+	if isControllerPod(podName, c.PodPrefix) {
+		template.Labels[roleLabel] = roleController
+		return template
+	}
+
+	template.Labels[roleLabel] = roleEngine
+	template.Spec.Hostname = podName
+	template.Spec.Subdomain = engineServiceName(c.PodPrefix)
+	return template
+}
+
+// ensureEngineService creates the headless Service engines are resolved through, unless it exists
+func ensureEngineService(ctx context.Context, clientset kubernetes.Interface, namespace, prefix string) error {
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      engineServiceName(prefix),
+			Namespace: namespace,
+			Labels: map[string]string{
+				appLabelKey:    appLabelValue,
+				podPrefixLabel: prefix,
+			},
+		},
+		Spec: v1.ServiceSpec{
+			ClusterIP: v1.ClusterIPNone,
+			Selector: map[string]string{
+				appLabelKey:    appLabelValue,
+				podPrefixLabel: prefix,
+				roleLabel:      roleEngine,
+			},
+			PublishNotReadyAddresses: true,
+			Ports: []v1.ServicePort{
+				{Name: "rmi-registry", Port: rmiRegistryPort, TargetPort: intstr.FromInt32(rmiRegistryPort)},
+				{Name: "rmi-server", Port: serverRMIPort, TargetPort: intstr.FromInt32(serverRMIPort)},
+			},
+		},
+	}
+
+	_, err := clientset.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+func deleteEngineService(ctx context.Context, clientset kubernetes.Interface, namespace, prefix string) error {
+	err := clientset.CoreV1().Services(namespace).Delete(ctx, engineServiceName(prefix), metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// getEngineHosts lists the engines of a session as <hostname>.<service>, the value of -R
+func getEngineHosts(ctx context.Context, clientset kubernetes.Interface, namespace, prefix string) ([]string, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,%s=%s,%s=%s,%s=true", appLabelKey, appLabelValue, podPrefixLabel, prefix, roleLabel, roleEngine, engineReadyLabel),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []string
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil || pod.Status.Phase != v1.PodRunning {
+			continue
+		}
+		hosts = append(hosts, pod.Spec.Hostname+"."+pod.Spec.Subdomain)
+	}
+	sort.Strings(hosts)
+
+	return hosts, nil
+}
+
+// getStartEngineCommand starts jmeter-server in the background and waits until its RMI object is registered
+func getStartEngineCommand(install jmeterInstall) string {
+	return fmt.Sprintf(
+		"cd %s && SERVER_PORT=%d nohup sh %s -Jserver.rmi.localport=%d %s %s > %s 2>&1 < /dev/null & "+
+			"for i in $(seq 1 60); do grep -q 'Created remote object' %s && exit 0; sleep 1; done; "+
+			"cat %s; exit 1",
+		workDir, rmiRegistryPort, install.bin("jmeter-server"), serverRMIPort, rmiSSLDisabled, rmiHostnameToPod, engineOutFile,
+		engineOutFile, engineOutFile)
+}
+
+// getControllerRunArgs points the controller to the engines and sends them the properties file
+func getControllerRunArgs(test TestInfo, hosts []string) []string {
+	return []string{
+		"-R " + strings.Join(hosts, ","),
+		"-G" + test.PropFileName,
+		"-Jclient.rmi.localport=" + strconv.Itoa(clientRMIPort),
+		rmiSSLDisabled,
+		rmiHostnameToPod,
+	}
+}
+
+// startEngine runs jmeter-server in a prepared engine pod
+func (c *Cluster) startEngine(ctx context.Context, pod *v1.Pod, podName string, ch chan<- ActionDone) error {
+	start := time.Now()
+	out, errOut, err := executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, getStartEngineCommand(c.jmeter))
+	if err != nil {
+		c.Logger.Error("failed to start jmeter-server", slog.String("pod", podName), slog.String("out", out), slog.String("errOut", errOut))
+		err = fmt.Errorf("failed to start jmeter-server: %w", err)
+		ch <- ActionDone{
+			PodName:  podName,
+			Name:     "starting jmeter-server",
+			Duration: time.Since(start),
+			Err:      err,
+		}
+		return err
+	}
+
+	patch := fmt.Sprintf(`{"metadata":{"labels":{%q:"true"}}}`, engineReadyLabel)
+	_, err = c.Clientset.CoreV1().Pods(c.Namespace).Patch(ctx, pod.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		err = fmt.Errorf("failed to mark engine as ready: %w", err)
+		ch <- ActionDone{
+			PodName:  podName,
+			Name:     "starting jmeter-server",
+			Duration: time.Since(start),
+			Err:      err,
+		}
+		return err
+	}
+
+	ch <- ActionDone{
+		PodName:  podName,
+		Name:     "starting jmeter-server",
+		Duration: time.Since(start),
+	}
+	return nil
+}
+
+// checkEngineProgress reports the engine's own log; an engine is done once the controller is
+func (c *Cluster) checkEngineProgress(ctx context.Context, testInfo TestInfo) (bool, string, error) {
+	pod, err := c.PodsCache.TryGet(ctx, c.Logger, testInfo.PodName, c.Namespace, c.Clientset)
+	if err != nil {
+		c.Logger.Error(err.Error())
+		return true, "", c.withPodDiagnostics(ctx, testInfo.PodName, errors.New("failed to reach engine"))
+	}
+
+	logs, errOut, err := c.readLogIncrement(ctx, pod, testInfo.PodName, engineLogFile, &c.logs, maxLogChunk)
+	if err != nil {
+		c.Logger.Error(err.Error())
+		c.Logger.Error(errOut)
+		return true, logs, c.withPodDiagnostics(ctx, testInfo.PodName, errors.New("failed to read jmeter-server log. check pod's health"))
+	}
+
+	finished, err := c.controllerRun.get()
+	if err != nil && !finished {
+		return false, logs, fmt.Errorf("run state of the controller is unknown: %w", err)
+	}
+	return finished, logs, nil
+}
+
+// controllerRunState is the outcome of the latest progress check of the controller, so engines
+// do not each check it again. A failed controller run is reported on the controller only.
+type controllerRunState struct {
+	mu       sync.Mutex
+	finished bool
+	err      error
+}
+
+func (s *controllerRunState) set(finished bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finished, s.err = finished, err
+}
+
+func (s *controllerRunState) get() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.finished, s.err
+}
@@ -1,29 +1,50 @@
 package kubeutils
 
 import (
+	"archive/tar"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"log/slog"
+	"io"
 	"os"
-	"os/exec"
+	"path"
 	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
-	core "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/remotecommand"
+	watchtools "k8s.io/client-go/tools/watch"
 )
 
-func executeRemoteCommand(ctx context.Context, restCfg *rest.Config, clientset *kubernetes.Clientset, pod *v1.Pod, command string) (string, string, error) {
+func executeRemoteCommand(ctx context.Context, restCfg *rest.Config, clientset kubernetes.Interface, pod *v1.Pod, command string) (string, string, error) {
 	buf := &bytes.Buffer{}
 	errBuf := &bytes.Buffer{}
+
+	err := streamRemoteCommand(ctx, restCfg, clientset, pod, command, nil, buf, errBuf)
+	if err != nil {
+		return "", "", err
+	}
+
+	return buf.String(), errBuf.String(), nil
+}
+
+// streamRemoteCommand runs a command in the pod over the exec subresource wiring stdin (if any)
+// and the output streams directly, so large payloads never have to be buffered in memory
+func streamRemoteCommand(ctx context.Context, restCfg *rest.Config, clientset kubernetes.Interface, pod *v1.Pod, command string, stdin io.Reader, stdout, stderr io.Writer) error {
 	request := clientset.CoreV1().RESTClient().
 		Post().
 		Namespace(pod.Namespace).
@@ -32,56 +53,256 @@ func executeRemoteCommand(ctx context.Context, restCfg *rest.Config, clientset *
 		SubResource("exec").
 		VersionedParams(&v1.PodExecOptions{
 			Command:   []string{"/bin/sh", "-c", command},
-			Container: pod.Name,
-			Stdin:     false,
+			Container: loadGeneratorContainerName(pod),
+			Stdin:     stdin != nil,
 			Stdout:    true,
 			Stderr:    true,
 			TTY:       false,
 		}, scheme.ParameterCodec)
 	exec, err := remotecommand.NewSPDYExecutor(restCfg, "POST", request.URL())
 	if err != nil {
-		return "", "", err
+		return err
 	}
 
 	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
-		Stdout: buf,
-		Stderr: errBuf,
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
 		Tty:    false,
 	})
 
 	if err != nil {
-		return "", "", fmt.Errorf("%w failed executing command %s on %v/%v", err, command, pod.Namespace, pod.Name)
+		return fmt.Errorf("%w failed executing command %s on %v/%v", err, command, pod.Namespace, pod.Name)
 	}
 
-	return buf.String(), errBuf.String(), nil
+	return nil
 }
 
-func switchLocalK8sContext(ctxName string) error {
-	switchCmd := exec.Command(
-		"kubectl",
-		"config",
-		"use-context",
-		ctxName,
-	)
+// uploadFile streams a single file into the pod as a tar archive unpacked by tar on the other end.
+// progress is called with the amount of bytes read from the local file so far.
+func uploadFile(ctx context.Context, restCfg *rest.Config, clientset kubernetes.Interface, pod *v1.Pod, localPath, remotePath string, progress func(int64)) (int64, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	remoteDir, remoteName := path.Split(remotePath)
+	if remoteDir == "" {
+		remoteDir = "."
+	}
+
+	reader, writer := io.Pipe()
+	counter := &countingReader{reader: file, onRead: progress}
 
-	switchCmd.Stdout = os.Stdout
-	switchCmd.Stderr = os.Stderr
+	go func() {
+		tw := tar.NewWriter(writer)
+		err := tw.WriteHeader(&tar.Header{
+			Name:    remoteName,
+			Mode:    0644,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+		if err == nil {
+			_, err = io.Copy(tw, counter)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		writer.CloseWithError(err)
+	}()
 
-	err := switchCmd.Run()
+	errBuf := &bytes.Buffer{}
+	cmd := getUnpackUploadCommand(remoteDir)
+	err = streamRemoteCommand(ctx, restCfg, clientset, pod, cmd, reader, io.Discard, errBuf)
+	reader.Close()
 	if err != nil {
-		slog.Error(err.Error())
+		return counter.total, fmt.Errorf("%w: %s", err, errBuf.String())
 	}
-	return nil
+
+	return counter.total, nil
+}
+
+// downloadFile streams a file out of the pod with cat, then checks that the size and sha256 checksum
+// of the local copy match the ones computed in the pod. The file is written next to localPath first
+// and only moved in place once verified.
+func downloadFile(ctx context.Context, restCfg *rest.Config, clientset kubernetes.Interface, pod *v1.Pod, remotePath, localPath string, progress func(int64)) (int64, error) {
+	infoOut, errOut, err := executeRemoteCommand(ctx, restCfg, clientset, pod, getFileInfoCommand(remotePath))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", err, errOut)
+	}
+
+	expectedSize, expectedHash, err := parseFileInfo(infoOut)
+	if err != nil {
+		return 0, err
+	}
+
+	partPath := localPath + ".part"
+	file, err := os.Create(partPath)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(partPath)
+
+	streamCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	hasher := sha256.New()
+	counter := &countingWriter{onWrite: progress, lastWrite: time.Now()}
+	go watchTransferStall(streamCtx, cancel, counter)
+
+	errBuf := &bytes.Buffer{}
+	out := io.MultiWriter(file, hasher, counter)
+	err = streamRemoteCommand(streamCtx, restCfg, clientset, pod, getStreamFileCommand(remotePath), nil, out, errBuf)
+	closeErr := file.Close()
+	if err != nil {
+		if cause := context.Cause(streamCtx); cause != nil && !errors.Is(cause, context.Canceled) {
+			err = cause
+		}
+		return counter.total, fmt.Errorf("%w: %s", err, errBuf.String())
+	}
+	if closeErr != nil {
+		// a failed flush leaves a truncated file behind even though the size and checksum matched
+		return counter.total, fmt.Errorf("failed to write %s: %w", partPath, closeErr)
+	}
+
+	if counter.total != expectedSize {
+		return counter.total, fmt.Errorf("size mismatch for %s: expected %d bytes, got %d", remotePath, expectedSize, counter.total)
+	}
+
+	actualHash := hex.EncodeToString(hasher.Sum(nil))
+	if actualHash != expectedHash {
+		return counter.total, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", remotePath, expectedHash, actualHash)
+	}
+
+	return counter.total, os.Rename(partPath, localPath)
+}
+
+func parseFileInfo(out string) (int64, string, error) {
+	fields := strings.Fields(out)
+	if len(fields) < 2 {
+		return 0, "", fmt.Errorf("unexpected file info output: %q", out)
+	}
+
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("unexpected file size %q", fields[0])
+	}
+
+	return size, fields[1], nil
+}
+
+// watchTransferStall cancels a transfer when no data arrived for transferStallTimeout
+func watchTransferStall(ctx context.Context, cancel context.CancelCauseFunc, counter *countingWriter) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if counter.idleFor() > transferStallTimeout {
+				cancel(fmt.Errorf("transfer stalled: no data received for %s", transferStallTimeout))
+				return
+			}
+		}
+	}
+}
+
+type countingWriter struct {
+	total   int64
+	onWrite func(int64)
+
+	mu        sync.Mutex
+	lastWrite time.Time
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.total += int64(len(p))
+	w.mu.Lock()
+	w.lastWrite = time.Now()
+	w.mu.Unlock()
+
+	if w.onWrite != nil && len(p) > 0 {
+		w.onWrite(w.total)
+	}
+	return len(p), nil
+}
+
+func (w *countingWriter) idleFor() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return time.Since(w.lastWrite)
+}
+
+// reportTransferProgress sends an intermediate ActionDone every transferProgressStep bytes
+func reportTransferProgress(ch chan<- ActionDone, podName, stepName string) func(int64) {
+	if ch == nil {
+		return nil
+	}
+
+	start := time.Now()
+	var reported int64
+	return func(total int64) {
+		if total-reported < transferProgressStep {
+			return
+		}
+		reported = total
+		ch <- ActionDone{
+			PodName:  podName,
+			Name:     stepName,
+			Duration: time.Since(start),
+			Bytes:    total,
+		}
+	}
+}
+
+type countingReader struct {
+	reader io.Reader
+	total  int64
+	onRead func(int64)
 }
 
-func deletePod(ctx context.Context, clientset *kubernetes.Clientset, namespace, podName string) error {
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.total += int64(n)
+	if r.onRead != nil && n > 0 {
+		r.onRead(r.total)
+	}
+	return n, err
+}
+
+func deletePod(ctx context.Context, clientset kubernetes.Interface, namespace, podName string) error {
 	deletePolicy := metav1.DeletePropagationBackground
 	return clientset.CoreV1().Pods(namespace).Delete(ctx, podName, metav1.DeleteOptions{
 		PropagationPolicy: &deletePolicy,
 	})
 }
 
-func checkClusterConnection(ctx context.Context, clientset *kubernetes.Clientset) (bool, error) {
+// waitForPodGone polls until no pod carries podName in its labels any more, so the name can be created again
+func waitForPodGone(ctx context.Context, clientset kubernetes.Interface, namespace, podName string, timeout time.Duration) error {
+	err := wait.PollUntilContextTimeout(ctx, time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: podNameLabel + "=" + podName,
+		})
+		if err != nil {
+			return false, err
+		}
+		return len(pods.Items) == 0, nil
+	})
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("pod %s is still terminating after %s: %w", podName, timeout, err)
+	}
+	return err
+}
+
+func checkClusterConnection(ctx context.Context, clientset kubernetes.Interface) (bool, error) {
 	path := "/healthz"
 	content, err := clientset.Discovery().RESTClient().Get().AbsPath(path).DoRaw(ctx)
 	if err != nil {
@@ -96,63 +317,125 @@ func checkClusterConnection(ctx context.Context, clientset *kubernetes.Clientset
 	return true, nil
 }
 
-func createPod(ctx context.Context, clientset *kubernetes.Clientset, namespace, podName string, keepAliveSec int) (*v1.Pod, error) {
-	podDefinition := getPodObject(namespace, podName, keepAliveSec)
+func createPod(ctx context.Context, clientset kubernetes.Interface, namespace, podName, image string, keepAliveSec int, template *v1.Pod, readyTimeout time.Duration) (*v1.Pod, error) {
+	podDefinition := getPodObject(namespace, podName, image, keepAliveSec, template)
 	pod, err := clientset.CoreV1().Pods(namespace).Create(ctx, podDefinition, metav1.CreateOptions{})
 	if err != nil {
 		return nil, err
 	}
 
-	onlineCtx, cancel := context.WithTimeout(ctx, time.Second*30)
+	return waitForPodRunning(ctx, clientset, namespace, podName, metav1.ListOptions{
+		FieldSelector: "metadata.name=" + pod.Name,
+	}, readyTimeout)
+}
+
+// waitForPodRunning watches the first pod matching opts until it is running with all containers ready.
+// When that does not happen within timeout, or the pod ends up in a state it cannot recover from,
+// a *PodNotReadyError with the reason observed last is returned.
+func waitForPodRunning(ctx context.Context, clientset kubernetes.Interface, namespace, podName string, opts metav1.ListOptions, timeout time.Duration) (*v1.Pod, error) {
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = opts.FieldSelector
+			options.LabelSelector = opts.LabelSelector
+			return clientset.CoreV1().Pods(namespace).List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = opts.FieldSelector
+			options.LabelSelector = opts.LabelSelector
+			return clientset.CoreV1().Pods(namespace).Watch(ctx, options)
+		},
+	}
+
+	readyCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	for {
-		select {
-		case <-onlineCtx.Done():
-			return nil, errors.New("cancelled")
-		default:
-			status, err := clientset.CoreV1().Pods(namespace).Get(ctx, pod.Name, metav1.GetOptions{})
-			if err != nil {
-				slog.Error(err.Error())
-				time.Sleep(time.Second * 3)
-				continue
-			}
 
-			hasNotReadyContainers := slices.ContainsFunc(
-				status.Status.ContainerStatuses,
-				func(c v1.ContainerStatus) bool { return c.Ready == false })
+	var lastSeen *v1.Pod
+	event, err := watchtools.UntilWithSync(readyCtx, lw, &v1.Pod{}, nil, func(e watch.Event) (bool, error) {
+		pod, ok := e.Object.(*v1.Pod)
+		if !ok {
+			return false, nil
+		}
+		lastSeen = pod
 
-			if !hasNotReadyContainers && status.Status.Phase == v1.PodRunning {
-				return status, nil
-			}
+		if e.Type == watch.Deleted {
+			return false, &PodNotReadyError{PodName: podName, Phase: pod.Status.Phase, Reason: "Deleted", Message: "pod was deleted while starting"}
+		}
+
+		if isPodReady(pod) {
+			return true, nil
+		}
+
+		if notReady := getPodNotReadyReason(podName, pod); notReady.fatal {
+			return false, notReady
+		}
 
-			time.Sleep(time.Second * 3)
+		return false, nil
+	})
+
+	if err != nil {
+		var notReady *PodNotReadyError
+		if errors.As(err, &notReady) {
+			return nil, notReady
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		notReady = &PodNotReadyError{PodName: podName, Reason: "Timeout", Message: "pod was never observed"}
+		if lastSeen != nil {
+			notReady = getPodNotReadyReason(podName, lastSeen)
 		}
+		notReady.Message = fmt.Sprintf("not ready after %s: %s", timeout, notReady.Message)
+		return nil, notReady
 	}
+
+	return event.Object.(*v1.Pod), nil
 }
 
-func getPodObject(namespace, podName string, keepAliveSec int) *core.Pod {
-	return &core.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      podName,
-			Namespace: namespace,
-			Labels: map[string]string{
-				"app": "jmeter_pod",
-			},
-		},
-		Spec: core.PodSpec{
-			// Restarts are pointless since a pod will be erased on restart
-			RestartPolicy: core.RestartPolicyNever,
-			Containers: []core.Container{
-				{
-					Name:            podName,
-					Image:           "ubuntu:22.04",
-					ImagePullPolicy: core.PullIfNotPresent,
-					Command: []string{
-						"sleep",
-						strconv.Itoa(keepAliveSec),
-					},
-				},
-			},
-		},
+func isPodReady(pod *v1.Pod) bool {
+	hasNotReadyContainers := slices.ContainsFunc(
+		pod.Status.ContainerStatuses,
+		func(c v1.ContainerStatus) bool { return c.Ready == false })
+
+	return !hasNotReadyContainers && pod.Status.Phase == v1.PodRunning
+}
+
+// fatalWaitingReasons will not go away by waiting longer
+var fatalWaitingReasons = []string{"CrashLoopBackOff", "InvalidImageName", "CreateContainerConfigError", "CreateContainerError"}
+
+// getPodNotReadyReason picks the most specific explanation of why a pod is not ready
+func getPodNotReadyReason(podName string, pod *v1.Pod) *PodNotReadyError {
+	notReady := &PodNotReadyError{PodName: podName, Phase: pod.Status.Phase, Reason: string(pod.Status.Phase)}
+
+	if pod.Status.Phase == v1.PodFailed || pod.Status.Phase == v1.PodSucceeded {
+		notReady.Reason = pod.Status.Reason
+		notReady.Message = pod.Status.Message
+		notReady.fatal = true
+		if notReady.Reason == "" {
+			notReady.Reason = string(pod.Status.Phase)
+		}
 	}
+
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodScheduled && cond.Status == v1.ConditionFalse {
+			notReady.Reason = cond.Reason
+			notReady.Message = cond.Message
+		}
+	}
+
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" && cs.State.Waiting.Reason != "ContainerCreating" {
+			notReady.Reason = cs.State.Waiting.Reason
+			notReady.Message = cs.State.Waiting.Message
+			notReady.fatal = notReady.fatal || slices.Contains(fatalWaitingReasons, cs.State.Waiting.Reason)
+		}
+		if cs.State.Terminated != nil {
+			notReady.Reason = cs.State.Terminated.Reason
+			notReady.Message = cs.State.Terminated.Message
+			notReady.fatal = true
+		}
+	}
+
+	return notReady
 }
@@ -0,0 +1,89 @@
+package kubeutils
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// OrchestratorPod is a load generator pod found in any namespace of a cluster
+type OrchestratorPod struct {
+	Name      string
+	Namespace string
+	Prefix    string
+	// Owner is "<kind>/<name>" of the controlling object, empty for bare pods
+	Owner     string
+	OwnerKind string
+	OwnerName string
+	Phase     v1.PodPhase
+	Age       time.Duration
+}
+
+// ListOrchestratorPods lists pods labelled as load generators across all namespaces.
+// selector narrows the list down further and is combined with the orchestrator label.
+func ListOrchestratorPods(ctx context.Context, clientset kubernetes.Interface, selector string) ([]OrchestratorPod, error) {
+	labelSelector := appLabelKey + "=" + appLabelValue
+	if selector != "" {
+		labelSelector += "," + selector
+	}
+
+	pods, err := clientset.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]OrchestratorPod, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		op := OrchestratorPod{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			Prefix:    pod.Labels[podPrefixLabel],
+			Phase:     pod.Status.Phase,
+			Age:       time.Since(pod.CreationTimestamp.Time),
+		}
+
+		// pods created before the prefix label existed
+		if op.Prefix == "" {
+			op.Prefix = getPodPrefix(loadGeneratorContainerName(&pod))
+		}
+
+		if owner := metav1.GetControllerOf(&pod); owner != nil {
+			op.OwnerKind = owner.Kind
+			op.OwnerName = owner.Name
+			op.Owner = owner.Kind + "/" + owner.Name
+		}
+
+		found = append(found, op)
+	}
+
+	return found, nil
+}
+
+// DeleteOrchestratorPod removes a load generator pod, or the Job owning it so it is not recreated
+func DeleteOrchestratorPod(ctx context.Context, clientset kubernetes.Interface, pod OrchestratorPod) error {
+	if pod.OwnerKind == "Job" {
+		err := deleteJob(ctx, clientset, pod.Namespace, pod.OwnerName)
+		// the Job may already be gone while its pod is still terminating
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return deletePod(ctx, clientset, pod.Namespace, pod.Name)
+}
+
+// getPodPrefix strips the "-N" index from a pod name
+func getPodPrefix(podName string) string {
+	idx := strings.LastIndex(podName, "-")
+	if idx <= 0 {
+		return podName
+	}
+	return podName[:idx]
+}
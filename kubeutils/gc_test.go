@@ -0,0 +1,94 @@
+package kubeutils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	batch "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestListOrchestratorPods(t *testing.T) {
+	created := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	isController := true
+	clientset := fake.NewSimpleClientset(
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name: "nightly-0", Namespace: "perf", CreationTimestamp: created,
+			Labels: map[string]string{appLabelKey: appLabelValue, podNameLabel: "nightly-0", podPrefixLabel: "nightly", "team": "perf"},
+		}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name: "old-1", Namespace: "default", CreationTimestamp: created,
+			Labels: map[string]string{appLabelKey: appLabelValue},
+		}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name: "jobs-2-x7k2p", Namespace: "perf", CreationTimestamp: created,
+			Labels:          map[string]string{appLabelKey: appLabelValue, podNameLabel: "jobs-2", podPrefixLabel: "jobs"},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Job", Name: "jobs-2", Controller: &isController}},
+		}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "perf", Labels: map[string]string{"app": "web"}}},
+		&batch.Job{ObjectMeta: metav1.ObjectMeta{Name: "jobs-2", Namespace: "perf"}},
+	)
+
+	pods, err := ListOrchestratorPods(context.Background(), clientset, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byName := make(map[string]OrchestratorPod)
+	for _, pod := range pods {
+		byName[pod.Name] = pod
+	}
+	if len(byName) != 3 || byName["web-0"].Name != "" {
+		t.Fatalf("got pods %v, want the three load generators", pods)
+	}
+	if byName["old-1"].Prefix != "old" {
+		t.Errorf("got prefix %q for a pod without the prefix label, want old", byName["old-1"].Prefix)
+	}
+	if job := byName["jobs-2-x7k2p"]; job.Owner != "Job/jobs-2" || job.Prefix != "jobs" {
+		t.Errorf("got owner %q and prefix %q", job.Owner, job.Prefix)
+	}
+	if age := byName["nightly-0"].Age; age < 2*time.Hour || age > 3*time.Hour {
+		t.Errorf("got age %s, want about 2h", age)
+	}
+
+	selected, err := ListOrchestratorPods(context.Background(), clientset, "team=perf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 1 || selected[0].Name != "nightly-0" {
+		t.Errorf("got %v with a selector, want nightly-0", selected)
+	}
+
+	if err := DeleteOrchestratorPod(context.Background(), clientset, byName["jobs-2-x7k2p"]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clientset.BatchV1().Jobs("perf").Get(context.Background(), "jobs-2", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("the owning Job was not deleted: %v", err)
+	}
+
+	// the Job is gone while its pod is still around, the pod is deleted itself
+	if err := DeleteOrchestratorPod(context.Background(), clientset, byName["jobs-2-x7k2p"]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clientset.CoreV1().Pods("perf").Get(context.Background(), "jobs-2-x7k2p", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("the pod of a deleted Job was not deleted: %v", err)
+	}
+}
+
+func TestGetPodPrefix(t *testing.T) {
+	tests := map[string]string{
+		"load-0":     "load",
+		"my-load-12": "my-load",
+		"standalone": "standalone",
+		"-1":         "-1",
+	}
+	for podName, want := range tests {
+		if got := getPodPrefix(podName); got != want {
+			t.Errorf("getPodPrefix(%q) = %q, want %q", podName, got, want)
+		}
+	}
+}
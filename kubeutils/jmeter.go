@@ -0,0 +1,191 @@
+package kubeutils
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// DefaultJMeterVersion is installed into pods unless configured otherwise
+const DefaultJMeterVersion = "5.6.3"
+
+// DefaultJMeterPlugins are installed into pods unless configured otherwise. They are downloaded directly,
+// Plugins Manager is only set up when plugins are asked for by ID
+var DefaultJMeterPlugins = []JMeterPlugin{{URL: "https://jmeter-plugins.org/files/packages/jpgc-casutg-2.10.zip"}}
+
+// Plugins Manager and its command line runner, used to install plugins by ID
+const (
+	pluginsManagerVersion = "1.10"
+	cmdRunnerVersion      = "2.3"
+	pluginsManagerJar     = "jmeter-plugins-manager-" + pluginsManagerVersion + ".jar"
+	cmdRunnerJar          = "cmdrunner-" + cmdRunnerVersion + ".jar"
+	pluginsManagerURL     = "https://repo1.maven.org/maven2/kg/apc/jmeter-plugins-manager/" + pluginsManagerVersion + "/" + pluginsManagerJar
+	cmdRunnerURL          = "https://repo1.maven.org/maven2/kg/apc/cmdrunner/" + cmdRunnerVersion + "/" + cmdRunnerJar
+)
+
+var (
+	jmeterVersionPattern = regexp.MustCompile(`^\d+\.\d+(\.\d+)?$`)
+	pluginIDPattern      = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+)
+
+// JMeterPlugin is either a JMeter Plugins Manager ID with an optional version or a URL of a jar or zip
+type JMeterPlugin struct {
+	ID string
+	// Version of a Plugins Manager plugin, the latest one when empty
+	Version string
+	// URL of a jar dropped into lib/ext as is, or of a zip unpacked into JMeter home
+	URL string
+}
+
+func (p JMeterPlugin) isZip() bool {
+	return strings.HasSuffix(p.URL, ".zip")
+}
+
+func (p JMeterPlugin) String() string {
+	switch {
+	case p.URL != "":
+		return p.URL
+	case p.Version != "":
+		return p.ID + "=" + p.Version
+	default:
+		return p.ID
+	}
+}
+
+// ParseJMeterPlugins parses a spec such as "jpgc-casutg=2.10,jpgc-tst,https://host/websocket-samplers.jar".
+// A zip URL is a package laid out like JMeter home, as published on jmeter-plugins.org.
+func ParseJMeterPlugins(spec string) ([]JMeterPlugin, error) {
+	var plugins []JMeterPlugin
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if strings.Contains(item, "://") {
+			u, err := url.Parse(item)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") ||
+				(!strings.HasSuffix(u.Path, ".jar") && !strings.HasSuffix(u.Path, ".zip")) {
+				return nil, fmt.Errorf("invalid plugin %q: expected an http(s) URL of a .jar or .zip file", item)
+			}
+			plugins = append(plugins, JMeterPlugin{URL: item})
+			continue
+		}
+
+		id, version, _ := strings.Cut(item, "=")
+		if !pluginIDPattern.MatchString(id) || (version != "" && !pluginIDPattern.MatchString(version)) {
+			return nil, fmt.Errorf("invalid plugin %q: expected <plugins manager id>[=<version>] or a jar or zip URL", item)
+		}
+		plugins = append(plugins, JMeterPlugin{ID: id, Version: version})
+	}
+
+	return plugins, nil
+}
+
+func newJMeterInstall(cfg ClusterConfig) (jmeterInstall, error) {
+	if cfg.JMeterHome != "" {
+		return jmeterInstall{home: strings.TrimSuffix(cfg.JMeterHome, "/"), prebuilt: true}, nil
+	}
+
+	version := cfg.JMeterVersion
+	if version == "" {
+		version = DefaultJMeterVersion
+	}
+	if !jmeterVersionPattern.MatchString(version) {
+		return jmeterInstall{}, fmt.Errorf("invalid JMeter version %q", version)
+	}
+
+	install := jmeterInstall{
+		home:    workDir + "/" + jmeterDistName(version),
+		version: version,
+		plugins: cfg.JMeterPlugins,
+	}
+
+	if cfg.ArtifactCacheDir != "" {
+		cache, err := loadArtifactCache(cfg.ArtifactCacheDir, version, cfg.JMeterPlugins, cfg.JDKArchive)
+		if err != nil {
+			return jmeterInstall{}, err
+		}
+		install.cache = cache
+	}
+
+	return install, nil
+}
+
+func (j jmeterInstall) bin(script string) string {
+	return j.home + "/bin/" + script
+}
+
+func jmeterDistName(version string) string {
+	return "apache-jmeter-" + version
+}
+
+// getJMeterInstallCommands downloads the chosen JMeter version and installs the plugins into it
+func getJMeterInstallCommands(install jmeterInstall) []remoteCommand {
+	archive := jmeterDistName(install.version) + ".tgz"
+
+	cmds := []remoteCommand{
+		{
+			displayName: "downloading JMeter " + install.version,
+			command: fmt.Sprintf("mkdir -p %s && cd %s && wget -c https://archive.apache.org/dist/jmeter/binaries/%s",
+				workDir, workDir, archive),
+			retryExitCodes: true,
+		},
+		{
+			displayName: "unarchiving JMeter and removing archive",
+			command:     fmt.Sprintf("cd %s && tar -xf %s && rm %s", workDir, archive, archive),
+		},
+	}
+
+	var managed []string
+	for _, plugin := range install.plugins {
+		if plugin.URL == "" {
+			managed = append(managed, plugin.String())
+			continue
+		}
+
+		if plugin.isZip() {
+			archive := path.Base(plugin.URL)
+			cmds = append(cmds, remoteCommand{
+				displayName:    "downloading plugin " + archive,
+				command:        fmt.Sprintf("cd %s && wget -c '%s'", workDir, plugin.URL),
+				retryExitCodes: true,
+			})
+			cmds = append(cmds, remoteCommand{
+				displayName: "unpacking plugin and removing archive",
+				command:     fmt.Sprintf("cd %s && unzip -o %s -d %s && rm %s", workDir, archive, install.home, archive),
+			})
+			continue
+		}
+
+		cmds = append(cmds, remoteCommand{
+			displayName:    "downloading plugin " + path.Base(plugin.URL),
+			command:        fmt.Sprintf("wget -c -P %s/lib/ext '%s'", install.home, plugin.URL),
+			retryExitCodes: true,
+		})
+	}
+
+	if len(managed) > 0 {
+		cmds = append(cmds, remoteCommand{
+			displayName: "installing Plugins Manager",
+			command: fmt.Sprintf("cd %s && wget -c -P lib/ext %s && wget -c -P lib %s && java -cp lib/ext/%s org.jmeterplugins.repository.PluginManagerCMDInstaller",
+				install.home, pluginsManagerURL, cmdRunnerURL, pluginsManagerJar),
+			retryExitCodes: true,
+		})
+
+		cmds = append(cmds, remoteCommand{
+			displayName:    "installing plugins " + strings.Join(managed, ", "),
+			command:        fmt.Sprintf("sh %s install %s", install.bin("PluginsManagerCMD.sh"), strings.Join(managed, ",")),
+			retryExitCodes: true,
+		})
+	}
+
+	cmds = append(cmds, remoteCommand{
+		displayName: "testing JMeter installation",
+		command:     install.bin("jmeter") + " --help",
+	})
+
+	return cmds
+}
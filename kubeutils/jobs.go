@@ -0,0 +1,119 @@
+package kubeutils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func createJob(ctx context.Context, clientset kubernetes.Interface, namespace, podName, image string, keepAliveSec, ttlSec int, template *core.Pod, readyTimeout time.Duration) (*core.Pod, error) {
+	jobDefinition, err := getJobObject(namespace, podName, image, keepAliveSec, ttlSec, template)
+	if err != nil {
+		return nil, err
+	}
+	_, err = clientset.BatchV1().Jobs(namespace).Create(ctx, jobDefinition, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return waitForPodRunning(ctx, clientset, namespace, podName, metav1.ListOptions{
+		LabelSelector: podNameLabel + "=" + podName,
+	}, readyTimeout)
+}
+
+// getJobObject wraps the regular pod definition into a Job. activeDeadlineSeconds replaces the
+// keep-alive sleep and ttlSecondsAfterFinished lets the cluster remove whatever is left behind.
+func getJobObject(namespace, podName, image string, keepAliveSec, ttlSec int, template *core.Pod) (*batch.Job, error) {
+	pod := getPodObject(namespace, podName, image, keepAliveSec, template)
+
+	container := loadGeneratorContainer(pod)
+	if container == nil {
+		return nil, fmt.Errorf("no load generator container in pod %s", podName)
+	}
+	container.Command = getJobRunnerCommand()
+
+	backoffLimit := int32(0)
+	activeDeadline := int64(keepAliveSec)
+	ttl := int32(ttlSec)
+
+	return &batch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: namespace,
+			Labels:    pod.Labels,
+		},
+		Spec: batch.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			ActiveDeadlineSeconds:   &activeDeadline,
+			TTLSecondsAfterFinished: &ttl,
+			Template: core.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      pod.Labels,
+					Annotations: pod.Annotations,
+				},
+				Spec: pod.Spec,
+			},
+		},
+	}, nil
+}
+
+// getJobRunnerCommand is the main process of a Job pod. It waits for the run trigger
+// and runs JMeter itself, so a run does not depend on an exec session staying open.
+// It exits once results are collected, the Job then completes and its TTL applies.
+func getJobRunnerCommand() []string {
+	script := fmt.Sprintf(
+		"mkdir -p %s && cd %s && "+
+			"while [ ! -f %s ]; do "+
+			"if [ -f %s ]; then rm -f %s; sh -c '%s'; fi; "+
+			"sleep 1; "+
+			"done",
+		workDir, workDir, runFinishFile, runTriggerFile, runTriggerFile, getSupervisedRunScript())
+
+	return []string{"/bin/sh", "-c", script}
+}
+
+// checkJobStatus returns an error describing why the Job failed, if it did
+func checkJobStatus(ctx context.Context, clientset kubernetes.Interface, namespace, jobName string) error {
+	job, err := clientset.BatchV1().Jobs(namespace).Get(ctx, jobName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get job %s: %w", jobName, err)
+	}
+
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batch.JobFailed && cond.Status == core.ConditionTrue {
+			return fmt.Errorf("job %s failed: %s: %s", jobName, cond.Reason, cond.Message)
+		}
+	}
+
+	return nil
+}
+
+func deleteJob(ctx context.Context, clientset kubernetes.Interface, namespace, jobName string) error {
+	deletePolicy := metav1.DeletePropagationBackground
+	return clientset.BatchV1().Jobs(namespace).Delete(ctx, jobName, metav1.DeleteOptions{
+		PropagationPolicy: &deletePolicy,
+	})
+}
+
+// findPodByLabel finds a live pod the orchestrator knows by podName
+func findPodByLabel(ctx context.Context, clientset kubernetes.Interface, namespace, podName string) (*core.Pod, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: podNameLabel + "=" + podName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp == nil && pod.Status.Phase == core.PodRunning {
+			return &pod, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no running pod found for %s", podName)
+}
@@ -0,0 +1,67 @@
+package kubeutils
+
+import (
+	"slices"
+	"testing"
+
+	core "k8s.io/api/core/v1"
+)
+
+func TestGetJobObject(t *testing.T) {
+	tests := []struct {
+		name       string
+		template   *core.Pod
+		containers []string
+	}{
+		{
+			name:       "no template",
+			containers: []string{"load-1"},
+		},
+		{
+			name:       "single container",
+			template:   &core.Pod{Spec: core.PodSpec{Containers: []core.Container{{Name: "main"}}}},
+			containers: []string{"load-1"},
+		},
+		{
+			name: "jmeter and sidecar",
+			template: &core.Pod{Spec: core.PodSpec{Containers: []core.Container{
+				{Name: templateContainerName},
+				{Name: "sidecar", Command: []string{"envoy"}},
+			}}},
+			containers: []string{"load-1", "sidecar"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := getJobObject("perf", "load-1", "", 600, 3600, tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			spec := job.Spec.Template.Spec
+			var names []string
+			for _, c := range spec.Containers {
+				names = append(names, c.Name)
+			}
+			if !slices.Equal(names, tt.containers) {
+				t.Fatalf("got containers %v, want %v", names, tt.containers)
+			}
+
+			for _, c := range spec.Containers {
+				runner := slices.Equal(c.Command, getJobRunnerCommand())
+				if runner != (c.Name == "load-1") {
+					t.Errorf("container %s has command %v", c.Name, c.Command)
+				}
+			}
+
+			if *job.Spec.BackoffLimit != 0 || *job.Spec.ActiveDeadlineSeconds != 600 || *job.Spec.TTLSecondsAfterFinished != 3600 {
+				t.Errorf("got backoff %d, deadline %d, ttl %d",
+					*job.Spec.BackoffLimit, *job.Spec.ActiveDeadlineSeconds, *job.Spec.TTLSecondsAfterFinished)
+			}
+			if job.Spec.Template.Labels[podNameLabel] != "load-1" || spec.RestartPolicy != core.RestartPolicyNever {
+				t.Errorf("got labels %v and restart policy %s", job.Spec.Template.Labels, spec.RestartPolicy)
+			}
+		})
+	}
+}
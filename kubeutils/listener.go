@@ -0,0 +1,222 @@
+package kubeutils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+// listenerDatabase is ignored by the receiver but InfluxDB 1.x clients always send one
+const listenerDatabase = "jmeter"
+
+// listenerServiceName is a Service without selector whose Endpoints point at the receiver
+// outside the cluster, so pods reach it through cluster DNS
+func listenerServiceName(prefix string) string {
+	return prefix + "-metrics"
+}
+
+// getListenerURL is the write endpoint Backend Listeners send to. An IP address is published
+// through the listener Service, a hostname is used as is.
+func getListenerURL(addr, namespace, prefix string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid listener address %q: %w", addr, err)
+	}
+
+	if net.ParseIP(host) != nil && namespace != "" {
+		host = fmt.Sprintf("%s.%s.svc", listenerServiceName(prefix), namespace)
+	}
+	return fmt.Sprintf("http://%s/write?db=%s", net.JoinHostPort(host, port), listenerDatabase), nil
+}
+
+// ensureListenerService publishes the receiver at ip:port inside the cluster, unless it is already
+func ensureListenerService(ctx context.Context, clientset kubernetes.Interface, namespace, prefix, addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if net.ParseIP(host) == nil {
+		return nil
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return err
+	}
+
+	labels := map[string]string{
+		appLabelKey:    appLabelValue,
+		podPrefixLabel: prefix,
+	}
+	service := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      listenerServiceName(prefix),
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{
+				{Name: "influx", Port: int32(port), TargetPort: intstr.FromInt(port)},
+			},
+		},
+	}
+	endpoints := &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      listenerServiceName(prefix),
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Subsets: []v1.EndpointSubset{{
+			Addresses: []v1.EndpointAddress{{IP: host}},
+			Ports:     []v1.EndpointPort{{Name: "influx", Port: int32(port)}},
+		}},
+	}
+
+	_, err = clientset.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	_, err = clientset.CoreV1().Endpoints(namespace).Create(ctx, endpoints, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// deleteListenerService removes the Service, its Endpoints are garbage collected with it
+func deleteListenerService(ctx context.Context, clientset kubernetes.Interface, namespace, prefix string) error {
+	err := clientset.CoreV1().Services(namespace).Delete(ctx, listenerServiceName(prefix), metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// backendListenerTemplate is an InfluxdbBackendListenerClient reporting every sampler plus the
+// "all" summary. application tells pods apart on the receiver.
+const backendListenerTemplate = `<BackendListener guiclass="BackendListenerGui" testclass="BackendListener" testname="Orchestrator live metrics" enabled="true">
+  <elementProp name="arguments" elementType="Arguments" guiclass="ArgumentsPanel" testclass="Arguments">
+    <collectionProp name="Arguments.arguments">%s
+    </collectionProp>
+  </elementProp>
+  <stringProp name="classname">org.apache.jmeter.visualizers.backend.influxdb.InfluxdbBackendListenerClient</stringProp>
+</BackendListener>
+<hashTree/>
+`
+
+const backendListenerArgTemplate = `
+      <elementProp name="%[1]s" elementType="Argument">
+        <stringProp name="Argument.name">%[1]s</stringProp>
+        <stringProp name="Argument.value">%[2]s</stringProp>
+        <stringProp name="Argument.metadata">=</stringProp>
+      </elementProp>`
+
+func getBackendListener(url, application string) string {
+	args := [][2]string{
+		{"influxdbMetricsSender", "org.apache.jmeter.visualizers.backend.influxdb.HttpMetricsSender"},
+		{"influxdbUrl", url},
+		{"application", application},
+		{"measurement", "jmeter"},
+		{"summaryOnly", "false"},
+		{"samplersRegex", ".*"},
+		{"percentiles", "90;95;99"},
+		{"testTitle", application},
+		{"eventTags", ""},
+	}
+
+	var b strings.Builder
+	for _, arg := range args {
+		fmt.Fprintf(&b, backendListenerArgTemplate, arg[0], html.EscapeString(arg[1]))
+	}
+	return fmt.Sprintf(backendListenerTemplate, b.String())
+}
+
+// injectBackendListener adds the Backend Listener to the hashTree of the TestPlan, which is
+// the last but one closing hashTree of a scenario
+func injectBackendListener(scenario []byte, url, application string) ([]byte, error) {
+	closing := []byte("</hashTree>")
+	outer := bytes.LastIndex(scenario, closing)
+	if outer < 0 {
+		return nil, errors.New("scenario has no test plan")
+	}
+	testPlan := bytes.LastIndex(scenario[:outer], closing)
+	if testPlan < 0 {
+		return nil, errors.New("scenario has no test plan")
+	}
+
+	var b bytes.Buffer
+	b.Write(scenario[:testPlan])
+	b.WriteString(getBackendListener(url, application))
+	b.Write(scenario[testPlan:])
+	return b.Bytes(), nil
+}
+
+// withBackendListener returns the test uploads with the scenario replaced by a temporary copy
+// reporting to listenerURL. cleanup removes the copy once it is uploaded.
+func withBackendListener(test TestInfo, listenerURL string) (uploads []fileTransfer, cleanup func(), err error) {
+	uploads = getTestUploadTransfers(test)
+	cleanup = func() {}
+	if listenerURL == "" {
+		return uploads, cleanup, nil
+	}
+
+	scenario, err := os.ReadFile(test.ScenarioFileName)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	injected, err := injectBackendListener(scenario, listenerURL, test.PodName)
+	if err != nil {
+		return nil, cleanup, fmt.Errorf("failed to add backend listener to %s: %w", test.ScenarioFileName, err)
+	}
+
+	tmp, err := os.CreateTemp("", "scenario-*.jmx")
+	if err != nil {
+		return nil, cleanup, err
+	}
+	cleanup = func() { os.Remove(tmp.Name()) }
+	_, err = tmp.Write(injected)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return nil, func() {}, err
+	}
+
+	for i := range uploads {
+		if uploads[i].localPath == test.ScenarioFileName {
+			uploads[i].localPath = tmp.Name()
+		}
+	}
+	return uploads, cleanup, nil
+}
+
+// sendListenerMetrics posts a window of metrics the way InfluxdbBackendListenerClient does,
+// simulated pods use it to feed the receiver
+func sendListenerMetrics(url, application string, count, errs, avg, maxMs, threads int) error {
+	body := fmt.Sprintf(
+		"jmeter,application=%[1]s,transaction=all,statut=all count=%[2]di,countError=%[3]di,avg=%[4]d,max=%[5]d,pct90.0=%[6]d,pct95.0=%[7]d,pct99.0=%[8]d\n"+
+			"jmeter,application=%[1]s,transaction=internal minAT=%[9]di,maxAT=%[9]di,meanAT=%[9]di,startedT=%[9]di,endedT=0i\n",
+		strings.ReplaceAll(application, " ", `\ `), count, errs, avg, maxMs, avg*3/2, avg*2, maxMs*9/10, threads)
+
+	client := http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(url, "text/plain", strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
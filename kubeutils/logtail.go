@@ -0,0 +1,86 @@
+package kubeutils
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// at most this much of a log is read per check; when a log grew more than that, older lines are skipped
+const maxLogChunk = 1 << 20
+
+// results feed latency histograms, so much more of a JTL is read before samples are skipped
+const maxResultsChunk = 32 << 20
+
+// logOffsets remembers how far the log of every pod has been read
+type logOffsets struct {
+	mu    sync.Mutex
+	byPod map[string]int64
+}
+
+func (o *logOffsets) get(podName string) int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.byPod[podName]
+}
+
+func (o *logOffsets) set(podName string, offset int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.byPod == nil {
+		o.byPod = make(map[string]int64)
+	}
+	o.byPod[podName] = offset
+}
+
+// getReadLogIncrementCommand prints "<size> <start>" followed by the log from start on.
+// A log smaller than offset was truncated or recreated and is read from the beginning.
+func getReadLogIncrementCommand(logPath string, offset, maxChunk int64) string {
+	return fmt.Sprintf(
+		"f=%s; o=%d; s=$(stat -c %%s \"$f\" 2>/dev/null || echo 0); "+
+			"[ \"$s\" -lt \"$o\" ] && o=0; "+
+			"[ $((s-o)) -gt %d ] && o=$((s-%d)); "+
+			"echo \"$s $o\"; "+
+			"[ \"$s\" -gt \"$o\" ] && tail -c +$((o+1)) \"$f\" | head -c $((s-o)); "+
+			"exit 0",
+		logPath, offset, maxChunk, maxChunk)
+}
+
+// parseLogIncrement returns the complete lines read and the offset to continue from.
+// A trailing partial line is left for the next read.
+func parseLogIncrement(out string) (string, int64, error) {
+	header, content, _ := strings.Cut(out, "\n")
+	_, startField, found := strings.Cut(header, " ")
+	if !found {
+		return "", 0, fmt.Errorf("unexpected log header %q", header)
+	}
+
+	start, err := strconv.ParseInt(strings.TrimSpace(startField), 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("unexpected log header %q", header)
+	}
+
+	complete := content[:strings.LastIndex(content, "\n")+1]
+	return complete, start + int64(len(complete)), nil
+}
+
+// readLogIncrement returns the lines appended to logPath since the previous read recorded in offsets
+func (c *Cluster) readLogIncrement(ctx context.Context, pod *v1.Pod, podName, logPath string, offsets *logOffsets, maxChunk int64) (string, string, error) {
+	cmd := getReadLogIncrementCommand(logPath, offsets.get(podName), maxChunk)
+	out, errOut, err := executeRemoteCommand(ctx, c.RestCfg, c.Clientset, pod, cmd)
+	if err != nil {
+		return "", errOut, err
+	}
+
+	lines, next, err := parseLogIncrement(out)
+	if err != nil {
+		return "", errOut, err
+	}
+
+	offsets.set(podName, next)
+	return lines, errOut, nil
+}
@@ -0,0 +1,290 @@
+package kubeutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ClusterTarget is a context and namespace receiving a share of the pods of a session
+type ClusterTarget struct {
+	KubeCtxName string
+	Namespace   string
+	Pods        int
+}
+
+func (t ClusterTarget) String() string {
+	return t.KubeCtxName + "/" + t.Namespace
+}
+
+// ParseClusterTargets parses a spec such as "eu-west/load=3,us-east/load=2" (context/namespace=pods)
+func ParseClusterTargets(spec string) ([]ClusterTarget, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	var targets []ClusterTarget
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(spec, ",") {
+		location, count, found := strings.Cut(strings.TrimSpace(item), "=")
+		kubeCtx, namespace, hasNamespace := strings.Cut(location, "/")
+		if !found || !hasNamespace || kubeCtx == "" || namespace == "" {
+			return nil, fmt.Errorf("invalid target %q, expected context/namespace=pods", item)
+		}
+
+		pods, err := strconv.Atoi(count)
+		if err != nil || pods < 1 {
+			return nil, fmt.Errorf("invalid amount of pods in target %q", item)
+		}
+
+		target := ClusterTarget{KubeCtxName: kubeCtx, Namespace: namespace, Pods: pods}
+		if seen[target.String()] {
+			return nil, fmt.Errorf("target %s is listed twice", target)
+		}
+		seen[target.String()] = true
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+// TotalPods is how many pods a session over targets has
+func TotalPods(targets []ClusterTarget) int {
+	total := 0
+	for _, t := range targets {
+		total += t.Pods
+	}
+	return total
+}
+
+// MultiCluster spreads the pods of a session over several targets. Pod names stay <prefix>-N
+// across the session, every target holds a consecutive range of them.
+type MultiCluster struct {
+	Targets   []ClusterTarget
+	PodPrefix string
+
+	backends []Backend
+	// firstPod is the index of the first pod of every target
+	firstPod []int
+
+	mu sync.Mutex
+	// owners remembers the target of pods found by DiscoverPods, whose index may not match the ranges
+	owners map[string]int
+}
+
+var _ Backend = (*MultiCluster)(nil)
+
+// NewMultiCluster creates a backend for every target with newBackend, which gets the index
+// of the first pod the target holds
+func NewMultiCluster(prefix string, targets []ClusterTarget, newBackend func(target ClusterTarget, firstPod int) (Backend, error)) (*MultiCluster, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("no cluster targets")
+	}
+
+	m := &MultiCluster{
+		Targets:   targets,
+		PodPrefix: prefix,
+		owners:    make(map[string]int),
+	}
+
+	next := 0
+	for _, target := range targets {
+		backend, err := newBackend(target, next)
+		if err != nil {
+			return nil, fmt.Errorf("target %s: %w", target, err)
+		}
+		m.backends = append(m.backends, backend)
+		m.firstPod = append(m.firstPod, next)
+		next += target.Pods
+	}
+
+	return m, nil
+}
+
+// targetIndex finds the target holding a pod
+func (m *MultiCluster) targetIndex(podName string) (int, error) {
+	m.mu.Lock()
+	owner, ok := m.owners[podName]
+	m.mu.Unlock()
+	if ok {
+		return owner, nil
+	}
+
+	idx := strings.LastIndex(podName, "-")
+	n, err := strconv.Atoi(podName[idx+1:])
+	if idx < 0 || err != nil || podName[:idx] != m.PodPrefix {
+		return 0, fmt.Errorf("pod %s does not belong to session %s", podName, m.PodPrefix)
+	}
+
+	for i := len(m.firstPod) - 1; i >= 0; i-- {
+		if n >= m.firstPod[i] && n < m.firstPod[i]+m.Targets[i].Pods {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("pod %s is outside of every target", podName)
+}
+
+func (m *MultiCluster) backendOf(podName string) (Backend, error) {
+	i, err := m.targetIndex(podName)
+	if err != nil {
+		return nil, err
+	}
+	return m.backends[i], nil
+}
+
+func (m *MultiCluster) Ping(ctx context.Context) (bool, error) {
+	for i, backend := range m.backends {
+		connected, err := backend.Ping(ctx)
+		if err != nil {
+			return false, fmt.Errorf("target %s: %w", m.Targets[i], err)
+		}
+		if !connected {
+			return false, fmt.Errorf("target %s is not reachable", m.Targets[i])
+		}
+	}
+	return true, nil
+}
+
+func (m *MultiCluster) PodTarget(podName string) string {
+	i, err := m.targetIndex(podName)
+	if err != nil {
+		return ""
+	}
+	return m.Targets[i].String()
+}
+
+func (m *MultiCluster) CreatePod(ctx context.Context, podName string) error {
+	backend, err := m.backendOf(podName)
+	if err != nil {
+		return err
+	}
+	return backend.CreatePod(ctx, podName)
+}
+
+func (m *MultiCluster) PreparePod(ctx context.Context, testInfo TestInfo, ch chan<- ActionDone) error {
+	backend, err := m.backendOf(testInfo.PodName)
+	if err != nil {
+		return err
+	}
+	return backend.PreparePod(ctx, testInfo, ch)
+}
+
+func (m *MultiCluster) ExecInPod(ctx context.Context, podName, command string) (string, string, error) {
+	backend, err := m.backendOf(podName)
+	if err != nil {
+		return "", "", err
+	}
+	return backend.ExecInPod(ctx, podName, command)
+}
+
+func (m *MultiCluster) UploadToPod(ctx context.Context, podName, localPath, remotePath string, ch chan<- ActionDone) (int64, error) {
+	backend, err := m.backendOf(podName)
+	if err != nil {
+		return 0, err
+	}
+	return backend.UploadToPod(ctx, podName, localPath, remotePath, ch)
+}
+
+func (m *MultiCluster) DownloadFromPod(ctx context.Context, podName, remotePath, localPath string, ch chan<- ActionDone) (int64, error) {
+	backend, err := m.backendOf(podName)
+	if err != nil {
+		return 0, err
+	}
+	return backend.DownloadFromPod(ctx, podName, remotePath, localPath, ch)
+}
+
+func (m *MultiCluster) KickstartTestForPod(ctx context.Context, testInfo TestInfo) error {
+	backend, err := m.backendOf(testInfo.PodName)
+	if err != nil {
+		return err
+	}
+	return backend.KickstartTestForPod(ctx, testInfo)
+}
+
+func (m *MultiCluster) CheckProgress(ctx context.Context, testInfo TestInfo) (bool, string, error) {
+	backend, err := m.backendOf(testInfo.PodName)
+	if err != nil {
+		return true, "", err
+	}
+	return backend.CheckProgress(ctx, testInfo)
+}
+
+func (m *MultiCluster) TailResults(ctx context.Context, testInfo TestInfo) (string, error) {
+	backend, err := m.backendOf(testInfo.PodName)
+	if err != nil {
+		return "", err
+	}
+	return backend.TailResults(ctx, testInfo)
+}
+
+func (m *MultiCluster) GetPodResources(ctx context.Context, podName string) (PodResources, error) {
+	backend, err := m.backendOf(podName)
+	if err != nil {
+		return PodResources{}, err
+	}
+	return backend.GetPodResources(ctx, podName)
+}
+
+func (m *MultiCluster) CancelRunForPod(ctx context.Context, testInfo TestInfo) error {
+	backend, err := m.backendOf(testInfo.PodName)
+	if err != nil {
+		return err
+	}
+	return backend.CancelRunForPod(ctx, testInfo)
+}
+
+func (m *MultiCluster) ResetPodForNewRun(ctx context.Context, testInfo TestInfo) error {
+	backend, err := m.backendOf(testInfo.PodName)
+	if err != nil {
+		return err
+	}
+	return backend.ResetPodForNewRun(ctx, testInfo)
+}
+
+func (m *MultiCluster) CollectResultsFromPod(ctx context.Context, testInfo TestInfo, ch chan<- ActionDone) error {
+	backend, err := m.backendOf(testInfo.PodName)
+	if err != nil {
+		return err
+	}
+	return backend.CollectResultsFromPod(ctx, testInfo, ch)
+}
+
+func (m *MultiCluster) DeletePod(ctx context.Context, podName string) error {
+	backend, err := m.backendOf(podName)
+	if err != nil {
+		return err
+	}
+	return backend.DeletePod(ctx, podName)
+}
+
+func (m *MultiCluster) RollbackPod(ctx context.Context, podName string) error {
+	backend, err := m.backendOf(podName)
+	if err != nil {
+		return err
+	}
+	return backend.RollbackPod(ctx, podName)
+}
+
+// DiscoverPods collects the pods of the session from every target
+func (m *MultiCluster) DiscoverPods(ctx context.Context) ([]DiscoveredPod, error) {
+	var discovered []DiscoveredPod
+	for i, backend := range m.backends {
+		pods, err := backend.DiscoverPods(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("target %s: %w", m.Targets[i], err)
+		}
+
+		m.mu.Lock()
+		for _, pod := range pods {
+			m.owners[pod.Name] = i
+		}
+		m.mu.Unlock()
+		discovered = append(discovered, pods...)
+	}
+
+	sortByPodIndex(discovered)
+	return discovered, nil
+}
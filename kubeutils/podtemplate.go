@@ -0,0 +1,173 @@
+package kubeutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// Labels the orchestrator puts on every pod it creates
+const (
+	appLabelKey   = "app"
+	appLabelValue = "jmeter_pod"
+	// podNameLabel holds the name the orchestrator knows a pod by. It differs from
+	// the actual pod name when pods are created by Jobs
+	podNameLabel = "jmeter_pod_name"
+	// podPrefixLabel holds the prefix of the session a pod belongs to
+	podPrefixLabel = "jmeter_pod_prefix"
+)
+
+// templateContainerName marks the load generator container when a template defines several containers
+const templateContainerName = "jmeter"
+
+// LoadPodTemplate reads a Pod manifest used as a base for load generator pods and validates it.
+// Name, namespace, the app label, restart policy and the command of the load generator
+// container are always managed by the orchestrator; everything else is taken as is.
+func LoadPodTemplate(templatePath string) (*core.Pod, error) {
+	content, err := os.ReadFile(templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pod template: %w", err)
+	}
+
+	template := &core.Pod{}
+	if err := yaml.UnmarshalStrict(content, template); err != nil {
+		return nil, fmt.Errorf("invalid pod template %s: %w", templatePath, err)
+	}
+
+	if err := validatePodTemplate(template); err != nil {
+		return nil, fmt.Errorf("invalid pod template %s: %w", templatePath, err)
+	}
+
+	return template, nil
+}
+
+func validatePodTemplate(template *core.Pod) error {
+	if template.Kind != "" && template.Kind != "Pod" {
+		return fmt.Errorf("expected kind Pod, got %s", template.Kind)
+	}
+
+	if template.Spec.RestartPolicy != "" && template.Spec.RestartPolicy != core.RestartPolicyNever {
+		return errors.New("restartPolicy must be Never or left empty")
+	}
+
+	idx := loadGeneratorContainerIndex(template.Spec.Containers)
+	if idx < 0 && len(template.Spec.Containers) > 0 {
+		return fmt.Errorf("template has several containers, name the load generator one %q", templateContainerName)
+	}
+
+	if idx >= 0 {
+		resources := template.Spec.Containers[idx].Resources
+		for name, limit := range resources.Limits {
+			request, ok := resources.Requests[name]
+			if ok && request.Cmp(limit) > 0 {
+				return fmt.Errorf("%s request %s exceeds limit %s", name, request.String(), limit.String())
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadGeneratorContainerIndex finds the container of a template JMeter runs in: the one named "jmeter"
+// or the only one. Built pods rename it after the pod, use loadGeneratorContainer for those.
+func loadGeneratorContainerIndex(containers []core.Container) int {
+	for i, c := range containers {
+		if c.Name == templateContainerName {
+			return i
+		}
+	}
+
+	if len(containers) == 1 {
+		return 0
+	}
+	return -1
+}
+
+func getPodObject(namespace, podName, image string, keepAliveSec int, template *core.Pod) *core.Pod {
+	pod := &core.Pod{}
+	if template != nil {
+		pod.Spec = *template.Spec.DeepCopy()
+		pod.Labels = template.DeepCopy().Labels
+		pod.Annotations = template.DeepCopy().Annotations
+	}
+
+	pod.ObjectMeta.Name = podName
+	pod.ObjectMeta.Namespace = namespace
+	if pod.Labels == nil {
+		pod.Labels = make(map[string]string)
+	}
+	pod.Labels[appLabelKey] = appLabelValue
+	pod.Labels[podNameLabel] = podName
+	pod.Labels[podPrefixLabel] = getPodPrefix(podName)
+
+	// Restarts are pointless since a pod will be erased on restart
+	pod.Spec.RestartPolicy = core.RestartPolicyNever
+
+	container := core.Container{}
+	idx := loadGeneratorContainerIndex(pod.Spec.Containers)
+	if idx >= 0 {
+		container = pod.Spec.Containers[idx]
+	}
+
+	// Commands are executed in the container named after the pod
+	container.Name = podName
+	if image != "" {
+		container.Image = image
+	} else if container.Image == "" {
+		container.Image = DefaultPodImage
+	}
+	if container.ImagePullPolicy == "" {
+		container.ImagePullPolicy = core.PullIfNotPresent
+	}
+	container.Command = []string{
+		"sleep",
+		strconv.Itoa(keepAliveSec),
+	}
+	container.Args = nil
+
+	if idx >= 0 {
+		pod.Spec.Containers[idx] = container
+	} else {
+		pod.Spec.Containers = append(pod.Spec.Containers, container)
+	}
+
+	return pod
+}
+
+// loadGeneratorContainerName returns the container commands are executed in
+func loadGeneratorContainerName(pod *core.Pod) string {
+	if name, ok := pod.Labels[podNameLabel]; ok {
+		return name
+	}
+	return pod.Name
+}
+
+// loadGeneratorContainer returns the container of a built pod commands are executed in, nil when there is none
+func loadGeneratorContainer(pod *core.Pod) *core.Container {
+	name := loadGeneratorContainerName(pod)
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == name {
+			return &pod.Spec.Containers[i]
+		}
+	}
+	return nil
+}
+
+// dryRunPod submits a pod with server-side dry run, so schema, quota and admission
+// problems of a template show up before any pod is created
+func dryRunPod(ctx context.Context, clientset kubernetes.Interface, pod *core.Pod) error {
+	_, err := clientset.CoreV1().Pods(pod.Namespace).Create(ctx, pod, metav1.CreateOptions{
+		DryRun: []string{metav1.DryRunAll},
+	})
+	if err != nil {
+		return fmt.Errorf("pod template rejected by the cluster: %w", err)
+	}
+	return nil
+}
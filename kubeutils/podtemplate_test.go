@@ -0,0 +1,121 @@
+package kubeutils
+
+import (
+	"slices"
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestGetPodObject(t *testing.T) {
+	sidecar := core.Container{Name: "sidecar", Image: "busybox", Command: []string{"tail", "-f", "/dev/null"}}
+
+	tests := []struct {
+		name       string
+		template   *core.Pod
+		image      string
+		containers []string
+		wantImage  string
+		sidecar    bool
+	}{
+		{
+			name:       "no template",
+			containers: []string{"load-3"},
+			wantImage:  DefaultPodImage,
+		},
+		{
+			name:       "template without containers",
+			template:   &core.Pod{Spec: core.PodSpec{ServiceAccountName: "jmeter"}},
+			image:      "jmeter:5.6.3",
+			containers: []string{"load-3"},
+			wantImage:  "jmeter:5.6.3",
+		},
+		{
+			name:       "single container",
+			template:   &core.Pod{Spec: core.PodSpec{Containers: []core.Container{{Name: "main", Image: "custom:1"}}}},
+			containers: []string{"load-3"},
+			wantImage:  "custom:1",
+		},
+		{
+			name: "jmeter and sidecar",
+			template: &core.Pod{Spec: core.PodSpec{Containers: []core.Container{
+				sidecar,
+				{Name: templateContainerName, Image: "custom:2", Args: []string{"ignored"}},
+			}}},
+			containers: []string{"sidecar", "load-3"},
+			wantImage:  "custom:2",
+			sidecar:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := getPodObject("perf", "load-3", tt.image, 60, tt.template)
+
+			var names []string
+			for _, c := range pod.Spec.Containers {
+				names = append(names, c.Name)
+			}
+			if !slices.Equal(names, tt.containers) {
+				t.Fatalf("got containers %v, want %v", names, tt.containers)
+			}
+
+			container := loadGeneratorContainer(pod)
+			if container == nil {
+				t.Fatal("load generator container not found")
+			}
+			if container.Image != tt.wantImage {
+				t.Errorf("got image %s, want %s", container.Image, tt.wantImage)
+			}
+			if len(container.Command) != 2 || container.Command[0] != "sleep" || container.Command[1] != "60" || container.Args != nil {
+				t.Errorf("got command %v %v, want sleep 60", container.Command, container.Args)
+			}
+			if pod.Spec.RestartPolicy != core.RestartPolicyNever {
+				t.Errorf("got restart policy %s", pod.Spec.RestartPolicy)
+			}
+			if pod.Labels[appLabelKey] != appLabelValue || pod.Labels[podNameLabel] != "load-3" || pod.Labels[podPrefixLabel] != "load" {
+				t.Errorf("got labels %v", pod.Labels)
+			}
+			if tt.sidecar && pod.Spec.Containers[0].Command[0] != "tail" {
+				t.Errorf("sidecar was changed: %v", pod.Spec.Containers[0])
+			}
+		})
+	}
+}
+
+func TestGetPodObjectKeepsTemplate(t *testing.T) {
+	template := &core.Pod{Spec: core.PodSpec{Containers: []core.Container{{Name: templateContainerName}}}}
+	getPodObject("perf", "load-0", "", 60, template)
+
+	if template.Spec.Containers[0].Name != templateContainerName || template.Spec.Containers[0].Command != nil {
+		t.Errorf("template was modified: %v", template.Spec.Containers[0])
+	}
+}
+
+func TestValidatePodTemplate(t *testing.T) {
+	limited := core.Container{Name: templateContainerName, Resources: core.ResourceRequirements{
+		Requests: core.ResourceList{core.ResourceCPU: resource.MustParse("2")},
+		Limits:   core.ResourceList{core.ResourceCPU: resource.MustParse("1")},
+	}}
+
+	tests := []struct {
+		name     string
+		template *core.Pod
+		wantErr  bool
+	}{
+		{name: "empty", template: &core.Pod{}},
+		{name: "jmeter and sidecar", template: &core.Pod{Spec: core.PodSpec{Containers: []core.Container{{Name: "sidecar"}, {Name: templateContainerName}}}}},
+		{name: "several without jmeter", template: &core.Pod{Spec: core.PodSpec{Containers: []core.Container{{Name: "a"}, {Name: "b"}}}}, wantErr: true},
+		{name: "restart policy", template: &core.Pod{Spec: core.PodSpec{RestartPolicy: core.RestartPolicyAlways}}, wantErr: true},
+		{name: "request above limit", template: &core.Pod{Spec: core.PodSpec{Containers: []core.Container{limited}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePodTemplate(tt.template); (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package kubeutils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/rest"
+)
+
+// metrics-server serves PodMetrics here, read with a raw request to avoid the metrics client dependency
+const podMetricsPath = "/apis/metrics.k8s.io/v1beta1"
+
+// ErrMetricsUnavailable is returned when the cluster has no metrics API or has no sample of the pod yet
+var ErrMetricsUnavailable = errors.New("metrics API is not available")
+
+// PodResources is the usage of the load generator container next to its limits.
+// Limits are zero when the container has none.
+type PodResources struct {
+	CPUMilli         int64
+	MemoryBytes      int64
+	CPULimitMilli    int64
+	MemoryLimitBytes int64
+}
+
+// CPUPct is the CPU usage in percent of the limit, zero without a limit
+func (r PodResources) CPUPct() float64 {
+	if r.CPULimitMilli == 0 {
+		return 0
+	}
+	return float64(r.CPUMilli) * 100 / float64(r.CPULimitMilli)
+}
+
+// MemoryPct is the memory usage in percent of the limit, zero without a limit
+func (r PodResources) MemoryPct() float64 {
+	if r.MemoryLimitBytes == 0 {
+		return 0
+	}
+	return float64(r.MemoryBytes) * 100 / float64(r.MemoryLimitBytes)
+}
+
+// podMetrics is the part of metrics.k8s.io PodMetrics that is read
+type podMetrics struct {
+	Containers []struct {
+		Name  string            `json:"name"`
+		Usage map[string]string `json:"usage"`
+	} `json:"containers"`
+}
+
+// getPodResources reads the usage of the container JMeter runs in and takes its limits from the pod spec
+func getPodResources(ctx context.Context, client rest.Interface, pod *v1.Pod) (PodResources, error) {
+	var usage PodResources
+	container := loadGeneratorContainer(pod)
+	if container == nil {
+		return usage, fmt.Errorf("no load generator container in pod %s", pod.Name)
+	}
+
+	if limit, ok := container.Resources.Limits[v1.ResourceCPU]; ok {
+		usage.CPULimitMilli = limit.MilliValue()
+	}
+	if limit, ok := container.Resources.Limits[v1.ResourceMemory]; ok {
+		usage.MemoryLimitBytes = limit.Value()
+	}
+
+	raw, err := client.Get().
+		AbsPath(podMetricsPath, "namespaces", pod.Namespace, "pods", pod.Name).
+		DoRaw(ctx)
+	if apierrors.IsNotFound(err) || apierrors.IsServiceUnavailable(err) {
+		return usage, fmt.Errorf("%w: %v", ErrMetricsUnavailable, err)
+	}
+	if err != nil {
+		return usage, err
+	}
+
+	var metrics podMetrics
+	if err := json.Unmarshal(raw, &metrics); err != nil {
+		return usage, fmt.Errorf("failed to decode pod metrics: %w", err)
+	}
+
+	for _, c := range metrics.Containers {
+		if c.Name != container.Name {
+			continue
+		}
+		if cpu, err := resource.ParseQuantity(c.Usage["cpu"]); err == nil {
+			usage.CPUMilli = cpu.MilliValue()
+		}
+		if memory, err := resource.ParseQuantity(c.Usage["memory"]); err == nil {
+			usage.MemoryBytes = memory.Value()
+		}
+		return usage, nil
+	}
+
+	return usage, fmt.Errorf("%w: no sample for container %s yet", ErrMetricsUnavailable, container.Name)
+}
@@ -0,0 +1,46 @@
+package kubeutils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes/scheme"
+	fakerest "k8s.io/client-go/rest/fake"
+)
+
+func TestGetPodResources(t *testing.T) {
+	template := &core.Pod{Spec: core.PodSpec{Containers: []core.Container{
+		{Name: "sidecar"},
+		{Name: templateContainerName, Resources: core.ResourceRequirements{Limits: core.ResourceList{
+			core.ResourceCPU:    resource.MustParse("2"),
+			core.ResourceMemory: resource.MustParse("1Gi"),
+		}}},
+	}}}
+	pod := getPodObject("perf", "load-0", "", 60, template)
+
+	client := &fakerest.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fakerest.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			body := `{"containers": [
+				{"name": "sidecar", "usage": {"cpu": "900m", "memory": "900Mi"}},
+				{"name": "load-0", "usage": {"cpu": "500m", "memory": "256Mi"}}
+			]}`
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}, nil
+		}),
+	}
+
+	usage, err := getPodResources(context.Background(), client, pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := PodResources{CPUMilli: 500, MemoryBytes: 256 << 20, CPULimitMilli: 2000, MemoryLimitBytes: 1 << 30}
+	if usage != want {
+		t.Errorf("got %+v, want %+v", usage, want)
+	}
+}
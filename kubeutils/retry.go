@@ -0,0 +1,173 @@
+package kubeutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/exec"
+)
+
+// ErrorClass tells whether a failed step is worth another attempt
+type ErrorClass int
+
+const (
+	// ErrorPermanent will fail the same way again, e.g. a rejected request or a cancelled context
+	ErrorPermanent ErrorClass = iota
+	// ErrorTransient is a network or transport failure, API throttling or a server side error
+	ErrorTransient
+	// ErrorExitCode means the command ran and exited non-zero
+	ErrorExitCode
+	// ErrorNotFound means the pod or another object is gone
+	ErrorNotFound
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorTransient:
+		return "transient"
+	case ErrorExitCode:
+		return "exit code"
+	case ErrorNotFound:
+		return "not found"
+	default:
+		return "permanent"
+	}
+}
+
+// transport failures that surface as plain strings from the SPDY and HTTP/2 stacks
+var transientMessages = []string{
+	"stream reset",
+	"connection reset",
+	"broken pipe",
+	"unexpected eof",
+	"http2: ",
+	"error dialing backend",
+	"unable to upgrade connection",
+	"tls handshake timeout",
+	"i/o timeout",
+}
+
+// ClassifyError sorts an error of a remote command or an API call into an ErrorClass
+func ClassifyError(err error) ErrorClass {
+	var exitErr exec.ExitError
+	switch {
+	case err == nil, errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ErrorPermanent
+	case errors.As(err, &exitErr):
+		return ErrorExitCode
+	case apierrors.IsNotFound(err):
+		return ErrorNotFound
+	case apierrors.IsTooManyRequests(err), apierrors.IsServerTimeout(err), apierrors.IsTimeout(err),
+		apierrors.IsServiceUnavailable(err), apierrors.IsInternalError(err), apierrors.IsUnexpectedServerError(err):
+		return ErrorTransient
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE):
+		return ErrorTransient
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrorTransient
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, transient := range transientMessages {
+		if strings.Contains(msg, transient) {
+			return ErrorTransient
+		}
+	}
+	return ErrorPermanent
+}
+
+// DefaultRetryAttempts is how many times a step is tried unless configured otherwise
+const DefaultRetryAttempts = 4
+
+// RetryPolicy retries transient failures with exponential backoff and jitter
+type RetryPolicy struct {
+	// Attempts is the total amount of tries, 1 disables retries
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// RetryExitCodes also retries commands that exited non-zero, e.g. apt waiting for its lock
+	RetryExitCodes bool
+	// StepAttempts lets single steps such as apt raise Attempts. Off when attempts are chosen explicitly
+	StepAttempts bool
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:     DefaultRetryAttempts,
+		BaseDelay:    500 * time.Millisecond,
+		MaxDelay:     15 * time.Second,
+		StepAttempts: true,
+	}
+}
+
+// forStep applies the overrides of a single step
+func (p RetryPolicy) forStep(cmd remoteCommand) RetryPolicy {
+	if cmd.attempts > 0 && p.StepAttempts {
+		p.Attempts = cmd.attempts
+	}
+	p.RetryExitCodes = p.RetryExitCodes || cmd.retryExitCodes
+	return p
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	switch ClassifyError(err) {
+	case ErrorTransient:
+		return true
+	case ErrorExitCode:
+		return p.RetryExitCodes
+	default:
+		return false
+	}
+}
+
+// backoff is the delay before the next attempt: it doubles every time up to MaxDelay,
+// a random half of it is dropped so pods hitting the same hiccup do not retry in lockstep
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retry runs fn until it succeeds, fails with an error that is not retryable or runs out of attempts.
+// onRetry is called with every failure that is followed by another attempt.
+func (p RetryPolicy) retry(ctx context.Context, onRetry func(attempt int, err error), fn func() error) (int, error) {
+	attempts := max(p.Attempts, 1)
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return attempt, nil
+		}
+		if attempt >= attempts || !p.retryable(err) {
+			if attempt > 1 {
+				err = fmt.Errorf("%w (after %d attempts)", err, attempt)
+			}
+			return attempt, err
+		}
+
+		if onRetry != nil {
+			onRetry(attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempt, err
+		case <-time.After(p.backoff(attempt)):
+		}
+	}
+}
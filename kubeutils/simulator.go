@@ -0,0 +1,734 @@
+package kubeutils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Failures that can be injected into simulated pods
+const (
+	SimSlowPod       = "slow"
+	SimImagePull     = "image-pull"
+	SimUnschedulable = "unschedulable"
+	SimPrepareError  = "prepare-error"
+	SimExecError     = "exec-error"
+	SimVanish        = "vanish"
+	SimStall         = "stall"
+	SimNoResults     = "no-results"
+	SimFlaky         = "flaky"
+)
+
+// slow pods take this many times longer for every step
+const simSlowFactor = 5
+
+// SimulatorConfig controls timings of the simulated backend and which pods misbehave.
+// Failures maps a pod index (the number after the pod prefix) to a failure kind.
+type SimulatorConfig struct {
+	PodStartup   time.Duration
+	StepDuration time.Duration
+	RunDuration  time.Duration
+	Seed         int64
+	Failures     map[int]string
+}
+
+// Simulator is an in-memory Backend that fakes pods, JMeter runs and results.
+type Simulator struct {
+	Config    SimulatorConfig
+	PodPrefix string
+	Logger    slog.Logger
+
+	jmeter jmeterInstall
+	retry  RetryPolicy
+	// listenerURL is where simulated Backend Listeners send metrics, empty when disabled
+	listenerURL string
+
+	mu   sync.Mutex
+	pods map[string]*simPod
+}
+
+type simPod struct {
+	failure  string
+	files    map[string]bool
+	logs     strings.Builder
+	rnd      *rand.Rand
+	runStart time.Time
+	running  bool
+	finished bool
+	gone     bool
+	samples  int
+
+	// logOffset is how much of logs CheckProgress has returned so far
+	logOffset int
+	// jtl holds simulated samples, jtlOffset is how much of it TailResults has returned
+	jtl       strings.Builder
+	jtlOffset int
+}
+
+var _ Backend = (*Simulator)(nil)
+
+func NewSimulator(cfg ClusterConfig, simCfg SimulatorConfig, logger slog.Logger) (*Simulator, error) {
+	if cfg.PodTemplatePath != "" {
+		if _, err := LoadPodTemplate(cfg.PodTemplatePath); err != nil {
+			return nil, err
+		}
+	}
+
+	jmeter, err := newJMeterInstall(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	retry := cfg.Retry
+	if retry.Attempts == 0 {
+		retry = DefaultRetryPolicy()
+	}
+
+	var listenerURL string
+	if cfg.ListenerAddr != "" {
+		listenerURL, err = getListenerURL(cfg.ListenerAddr, "", cfg.PodPrefix)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Simulator{
+		Config:      simCfg,
+		PodPrefix:   cfg.PodPrefix,
+		Logger:      logger,
+		jmeter:      jmeter,
+		retry:       retry,
+		listenerURL: listenerURL,
+		pods:        make(map[string]*simPod),
+	}, nil
+}
+
+// ParseSimulatorFailures parses a spec such as "slow=0,vanish=2,stall=3" into pod index -> failure.
+func ParseSimulatorFailures(spec string) (map[int]string, error) {
+	failures := make(map[int]string)
+	if spec == "" {
+		return failures, nil
+	}
+
+	known := []string{SimSlowPod, SimImagePull, SimUnschedulable, SimPrepareError, SimExecError, SimVanish, SimStall, SimNoResults, SimFlaky}
+	for _, item := range strings.Split(spec, ",") {
+		kind, idx, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found {
+			return nil, fmt.Errorf("invalid failure %q: expected <kind>=<pod index>", item)
+		}
+
+		isKnown := false
+		for _, k := range known {
+			if k == kind {
+				isKnown = true
+			}
+		}
+		if !isKnown {
+			return nil, fmt.Errorf("unknown failure kind %q. Supported: %s", kind, strings.Join(known, ", "))
+		}
+
+		podIdx, err := strconv.Atoi(idx)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pod index in %q", item)
+		}
+		failures[podIdx] = kind
+	}
+
+	return failures, nil
+}
+
+func (s *Simulator) Ping(ctx context.Context) (bool, error) {
+	if err := s.sleep(ctx, "", 500*time.Millisecond); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Simulator) CreatePod(ctx context.Context, podName string) error {
+	s.mu.Lock()
+	pod := s.newPod(podName)
+	s.pods[podName] = pod
+	s.mu.Unlock()
+
+	if err := s.sleep(ctx, podName, s.Config.PodStartup); err != nil {
+		return err
+	}
+
+	switch pod.failure {
+	case SimImagePull:
+		return &PodNotReadyError{
+			PodName: podName,
+			Phase:   "Pending",
+			Reason:  "ImagePullBackOff",
+			Message: "Back-off pulling image \"" + DefaultPodImage + "\"",
+		}
+	case SimUnschedulable:
+		return &PodNotReadyError{
+			PodName: podName,
+			Phase:   "Pending",
+			Reason:  "Unschedulable",
+			Message: "0/3 nodes are available: 3 Insufficient cpu",
+		}
+	}
+
+	return nil
+}
+
+func (s *Simulator) PreparePod(ctx context.Context, testInfo TestInfo, ch chan<- ActionDone) error {
+	podCreationStart := time.Now()
+	if err := s.CreatePod(ctx, testInfo.PodName); err != nil {
+		ch <- ActionDone{
+			PodName:  testInfo.PodName,
+			Name:     "creating pod",
+			Duration: time.Since(podCreationStart),
+			Err:      err,
+		}
+		return err
+	}
+
+	ch <- ActionDone{
+		PodName:  testInfo.PodName,
+		Name:     "creating pod",
+		Duration: time.Since(podCreationStart),
+	}
+
+	pod, _ := s.getPod(testInfo.PodName)
+	for i, cmd := range getPodSetupCommands(s.jmeter) {
+		start := time.Now()
+		var transferred int64
+		onRetry := func(attempt int, err error) {
+			ch <- ActionDone{PodName: testInfo.PodName, Name: cmd.displayName, Duration: time.Since(start), Attempts: attempt, Err: err, Retrying: true}
+		}
+		tries := 0
+		attempts, err := s.retry.forStep(cmd).retry(ctx, onRetry, func() error {
+			tries++
+			if err := s.sleep(ctx, testInfo.PodName, s.Config.StepDuration); err != nil {
+				return err
+			}
+
+			if pod.failure == SimPrepareError && i == 1 {
+				return fmt.Errorf("command terminated with exit code 4 failed executing command %s on simulated/%s", cmd.command, testInfo.PodName)
+			}
+
+			// flaky pods lose the exec stream on the first try of every step
+			if pod.failure == SimFlaky && tries == 1 {
+				return fmt.Errorf("error reading from error stream: %w failed executing command %s on simulated/%s", syscall.ECONNRESET, cmd.command, testInfo.PodName)
+			}
+
+			if cmd.upload != nil {
+				var err error
+				transferred, err = s.UploadToPod(ctx, testInfo.PodName, cmd.upload.localPath, cmd.upload.remotePath, ch)
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			s.Logger.Error("failed to execute command: ", slog.Any("err", err.Error()))
+			ch <- ActionDone{PodName: testInfo.PodName, Name: cmd.displayName, Duration: time.Since(start), Attempts: attempts, Err: err}
+			return err
+		}
+
+		ch <- ActionDone{
+			PodName:  testInfo.PodName,
+			Name:     cmd.displayName,
+			Duration: time.Since(start),
+			Bytes:    transferred,
+			Attempts: attempts,
+		}
+	}
+
+	uploads, cleanup, err := withBackendListener(testInfo, s.listenerURL)
+	if err != nil {
+		s.Logger.Error("failed to prepare scenario: ", slog.Any("err", err.Error()))
+		return err
+	}
+	defer cleanup()
+
+	for _, upload := range uploads {
+		start := time.Now()
+		transferred, err := s.UploadToPod(ctx, testInfo.PodName, upload.localPath, upload.remotePath, ch)
+		if err != nil {
+			s.Logger.Error("failed to copy file to pod: ", slog.Any("err", err.Error()))
+			return err
+		}
+
+		ch <- ActionDone{
+			PodName:  testInfo.PodName,
+			Name:     upload.displayName,
+			Duration: time.Since(start),
+			Bytes:    transferred,
+		}
+	}
+
+	return nil
+}
+
+func (s *Simulator) ExecInPod(ctx context.Context, podName, command string) (string, string, error) {
+	pod, err := s.getPod(podName)
+	if err != nil {
+		return "", "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pod.failure == SimExecError && pod.running {
+		return "", "error: unable to upgrade connection", fmt.Errorf("stream error: stream ID 3; INTERNAL_ERROR failed executing command %s on simulated/%s", command, podName)
+	}
+
+	return "", "", nil
+}
+
+func (s *Simulator) UploadToPod(ctx context.Context, podName, localPath, remotePath string, ch chan<- ActionDone) (int64, error) {
+	pod, err := s.getPod(podName)
+	if err != nil {
+		return 0, err
+	}
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	pod.files[remotePath] = true
+	s.mu.Unlock()
+
+	return info.Size(), s.sleep(ctx, podName, s.Config.StepDuration/4)
+}
+
+func (s *Simulator) DownloadFromPod(ctx context.Context, podName, remotePath, localPath string, ch chan<- ActionDone) (int64, error) {
+	pod, err := s.getPod(podName)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	exists := pod.files[remotePath]
+	s.mu.Unlock()
+	if !exists {
+		return 0, fmt.Errorf("%s: no such file or directory", remotePath)
+	}
+
+	if err := s.sleep(ctx, podName, s.Config.StepDuration/2); err != nil {
+		return 0, err
+	}
+
+	if err := writeSimulatedResults(localPath, podName); err != nil {
+		return 0, err
+	}
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (s *Simulator) KickstartTestForPod(ctx context.Context, testInfo TestInfo) error {
+	pod, err := s.getPod(testInfo.PodName)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pod.runStart = time.Now()
+	pod.running = true
+	pod.finished = false
+	pod.files["/jmeter/run.sh"] = true
+	fmt.Fprintf(&pod.logs, "%s INFO o.a.j.JMeter: Loading file: %s\n", pod.runStart.Format(time.DateTime), testInfo.ScenarioFileName)
+	fmt.Fprintf(&pod.logs, "%s INFO o.a.j.e.StandardJMeterEngine: Running the test!\n", pod.runStart.Format(time.DateTime))
+	if pod.jtl.Len() == 0 {
+		pod.jtl.WriteString(simJTLHeader)
+	}
+
+	return nil
+}
+
+func (s *Simulator) CheckProgress(ctx context.Context, testInfo TestInfo) (bool, string, error) {
+	pod, err := s.getPod(testInfo.PodName)
+	if err != nil {
+		return true, "", errors.New("failed to reach pod")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elapsed := time.Since(pod.runStart)
+	switch pod.failure {
+	case SimVanish:
+		if pod.running && elapsed > s.Config.RunDuration/2 {
+			pod.gone = true
+			return true, "", errors.New("failed to reach pod: Evicted: The node was low on resource: memory")
+		}
+	case SimExecError:
+		if pod.running && elapsed > s.Config.RunDuration/3 {
+			return true, pod.readLogIncrement(), errors.New("failed to check run state. check pod's health: OOMKilled (exit code 137)")
+		}
+	case SimStall:
+		if pod.running && elapsed > s.Config.RunDuration/3 {
+			return false, pod.readLogIncrement(), errors.New("failed to check run state. check pod's health: stream error: stream ID 5; INTERNAL_ERROR")
+		}
+	}
+
+	if !pod.running {
+		return pod.finished, pod.readLogIncrement(), nil
+	}
+
+	s.appendSummary(testInfo.PodName, pod)
+	if elapsed < s.Config.RunDuration {
+		return false, pod.readLogIncrement(), nil
+	}
+
+	pod.running = false
+	pod.finished = true
+	fmt.Fprintf(&pod.logs, "%s INFO o.a.j.r.Summariser: Tidying up ...\n", time.Now().Format(time.DateTime))
+	fmt.Fprintf(&pod.logs, "%s INFO o.a.j.r.Summariser: ... end of run\n", time.Now().Format(time.DateTime))
+
+	if pod.failure == SimNoResults {
+		return true, pod.readLogIncrement(), errors.New("run did not produce results")
+	}
+
+	pod.files["/jmeter/"+resultsPath] = true
+	return true, pod.readLogIncrement(), nil
+}
+
+// readLogIncrement returns the log written since the previous call, the caller holds s.mu
+func (p *simPod) readLogIncrement() string {
+	logs := p.logs.String()[p.logOffset:]
+	p.logOffset = p.logs.Len()
+	return logs
+}
+
+func (s *Simulator) TailResults(ctx context.Context, testInfo TestInfo) (string, error) {
+	pod, err := s.getPod(testInfo.PodName)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lines := pod.jtl.String()[pod.jtlOffset:]
+	pod.jtlOffset = pod.jtl.Len()
+	return lines, nil
+}
+
+func (s *Simulator) PodTarget(podName string) string {
+	return "simulated"
+}
+
+// Simulated pods have these limits, slow pods run close to the CPU one
+const (
+	simCPULimitMilli    = 1000
+	simMemoryLimitBytes = 2 << 30
+)
+
+func (s *Simulator) GetPodResources(ctx context.Context, podName string) (PodResources, error) {
+	pod, err := s.getPod(podName)
+	if err != nil {
+		return PodResources{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage := PodResources{
+		CPUMilli:         20 + pod.rnd.Int63n(30),
+		MemoryBytes:      300<<20 + pod.rnd.Int63n(50<<20),
+		CPULimitMilli:    simCPULimitMilli,
+		MemoryLimitBytes: simMemoryLimitBytes,
+	}
+	if pod.running {
+		usage.CPUMilli = 300 + pod.rnd.Int63n(400)
+		usage.MemoryBytes = 700<<20 + pod.rnd.Int63n(300<<20)
+		if pod.failure == SimSlowPod {
+			usage.CPUMilli = 930 + pod.rnd.Int63n(70)
+		}
+	}
+	return usage, nil
+}
+
+func (s *Simulator) CancelRunForPod(ctx context.Context, testInfo TestInfo) error {
+	pod, err := s.getPod(testInfo.PodName)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pod.running = false
+	fmt.Fprintf(&pod.logs, "%s INFO o.a.j.e.StandardJMeterEngine: Stopping test\n", time.Now().Format(time.DateTime))
+
+	return nil
+}
+
+func (s *Simulator) ResetPodForNewRun(ctx context.Context, testInfo TestInfo) error {
+	pod, err := s.getPod(testInfo.PodName)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pod.running = false
+	pod.finished = false
+	pod.samples = 0
+	pod.logs.Reset()
+	pod.logOffset = 0
+	pod.jtl.Reset()
+	pod.jtlOffset = 0
+	delete(pod.files, "/jmeter/"+resultsPath)
+
+	return nil
+}
+
+func (s *Simulator) CollectResultsFromPod(ctx context.Context, testInfo TestInfo, ch chan<- ActionDone) error {
+	pod, err := s.getPod(testInfo.PodName)
+	if err != nil {
+		return err
+	}
+
+	packStart := time.Now()
+	s.mu.Lock()
+	hasResults := pod.files["/jmeter/"+resultsPath]
+	s.mu.Unlock()
+	if !hasResults {
+		return fmt.Errorf("tar: /jmeter/%s: Cannot stat: No such file or directory", strings.TrimSuffix(resultsPath, "/"))
+	}
+
+	download := getDownloadResultsTransfer(testInfo, s.PodPrefix)
+	if err := s.sleep(ctx, testInfo.PodName, s.Config.StepDuration/2); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	pod.files[download.remotePath] = true
+	s.mu.Unlock()
+
+	ch <- ActionDone{
+		PodName:  testInfo.PodName,
+		Name:     "pack results into archive",
+		Duration: time.Since(packStart),
+	}
+
+	downloadStart := time.Now()
+	transferred, err := s.DownloadFromPod(ctx, testInfo.PodName, download.remotePath, download.localPath, ch)
+	if err != nil {
+		s.Logger.Error("failed to download results from pod: ", slog.Any("err", err.Error()))
+		return err
+	}
+
+	ch <- ActionDone{
+		PodName:  testInfo.PodName,
+		Name:     download.displayName,
+		Duration: time.Since(downloadStart),
+		Bytes:    transferred,
+	}
+
+	return nil
+}
+
+func (s *Simulator) DeletePod(ctx context.Context, podName string) error {
+	if _, err := s.getPod(podName); err != nil {
+		return err
+	}
+
+	if err := s.sleep(ctx, podName, s.Config.PodStartup/2); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.pods, podName)
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *Simulator) RollbackPod(ctx context.Context, podName string) error {
+	if _, err := s.getPod(podName); err != nil {
+		return nil
+	}
+	return s.DeletePod(ctx, podName)
+}
+
+func (s *Simulator) DiscoverPods(ctx context.Context) ([]DiscoveredPod, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var discovered []DiscoveredPod
+	for name, pod := range s.pods {
+		if pod.gone || !isSessionPod(name, s.PodPrefix) {
+			continue
+		}
+
+		d := DiscoveredPod{Name: name, JMeterInstalled: true}
+		for file := range pod.files {
+			switch {
+			case strings.HasSuffix(file, ".jmx"):
+				d.ScenarioFileName = path.Base(file)
+			case strings.HasSuffix(file, ".properties"):
+				d.PropFileName = path.Base(file)
+			}
+		}
+		if pod.running {
+			d.Run = RunInProgress
+		} else if pod.finished {
+			d.Run = RunFinished
+		}
+		discovered = append(discovered, d)
+	}
+
+	sortByPodIndex(discovered)
+	return discovered, nil
+}
+
+func (s *Simulator) newPod(podName string) *simPod {
+	pod := &simPod{
+		files: make(map[string]bool),
+		rnd:   rand.New(rand.NewSource(s.Config.Seed + int64(len(s.pods)))),
+	}
+
+	idx := strings.LastIndex(podName, "-")
+	if n, err := strconv.Atoi(podName[idx+1:]); err == nil {
+		pod.failure = s.Config.Failures[n]
+		pod.rnd = rand.New(rand.NewSource(s.Config.Seed + int64(n)))
+	}
+
+	return pod
+}
+
+func (s *Simulator) getPod(podName string) (*simPod, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pod, ok := s.pods[podName]
+	if !ok || pod.gone {
+		return nil, fmt.Errorf("pods \"%s\" not found", podName)
+	}
+	return pod, nil
+}
+
+// sleep waits for d (longer for slow pods) or until ctx is cancelled
+func (s *Simulator) sleep(ctx context.Context, podName string, d time.Duration) error {
+	if podName != "" {
+		s.mu.Lock()
+		if pod, ok := s.pods[podName]; ok && pod.failure == SimSlowPod {
+			d *= simSlowFactor
+		}
+		s.mu.Unlock()
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// appendSummary writes a pair of summariser lines the way JMeter does every reporting interval
+func (s *Simulator) appendSummary(podName string, pod *simPod) {
+	now := time.Now()
+	batch := 20 + pod.rnd.Intn(40)
+	errs := 0
+	if pod.rnd.Intn(10) == 0 {
+		errs = pod.rnd.Intn(3) + 1
+	}
+	pod.samples += batch
+
+	elapsed := now.Sub(pod.runStart)
+	avg := 80 + pod.rnd.Intn(150)
+	appendSimulatedSamples(pod, now, batch, errs, avg)
+	if s.listenerURL != "" {
+		go s.sendListenerMetrics(podName, batch, errs, avg)
+	}
+	fmt.Fprintf(&pod.logs,
+		"%s INFO o.a.j.r.Summariser: summary + %6d in 00:00:03 = %6.1f/s Avg: %5d Min: %5d Max: %5d Err: %5d (%.2f%%) Active: 10 Started: 10 Finished: 0\n",
+		now.Format(time.DateTime), batch, float64(batch)/3, avg, avg/4, avg*3, errs, float64(errs)*100/float64(batch))
+	fmt.Fprintf(&pod.logs,
+		"%s INFO o.a.j.r.Summariser: summary = %6d in %s = %6.1f/s Avg: %5d Min: %5d Max: %5d Err: %5d (%.2f%%)\n",
+		now.Format(time.DateTime), pod.samples, formatClock(elapsed), float64(pod.samples)/max(elapsed.Seconds(), 1), avg, avg/4, avg*3, errs, float64(errs)*100/float64(pod.samples))
+}
+
+func (s *Simulator) sendListenerMetrics(podName string, batch, errs, avg int) {
+	if err := sendListenerMetrics(s.listenerURL, podName, batch, errs, avg, avg*3, 10); err != nil {
+		s.Logger.Error("failed to send listener metrics: ", slog.Any("err", err.Error()))
+	}
+}
+
+const simJTLHeader = "timeStamp,elapsed,label,responseCode,responseMessage,threadName,dataType,success,failureMessage," +
+	"bytes,sentBytes,grpThreads,allThreads,URL,Latency,IdleTime,Connect\n"
+
+var simLabels = []string{"GET /", "GET /search", "POST /login", "POST /cart"}
+
+// appendSimulatedSamples writes JTL lines for a batch of samples, latencies have a long tail around avg
+func appendSimulatedSamples(pod *simPod, now time.Time, batch, errs, avg int) {
+	for i := range batch {
+		elapsed := avg/2 + pod.rnd.Intn(avg)
+		if pod.rnd.Intn(20) == 0 {
+			elapsed *= 2 + pod.rnd.Intn(8)
+		}
+
+		code, message, success := "200", "OK", "true"
+		if i < errs {
+			code, message, success = "503", "Service Unavailable", "false"
+		}
+
+		fmt.Fprintf(&pod.jtl, "%d,%d,%s,%s,%s,Thread Group 1-%d,text,%s,,%d,%d,10,10,null,%d,0,%d\n",
+			now.UnixMilli(), elapsed, simLabels[pod.rnd.Intn(len(simLabels))], code, message, 1+i%10, success,
+			512+pod.rnd.Intn(4096), 128, elapsed*3/4, elapsed/10)
+	}
+}
+
+func formatClock(d time.Duration) string {
+	secs := int(d.Seconds())
+	return fmt.Sprintf("%02d:%02d:%02d", secs/3600, secs%3600/60, secs%60)
+}
+
+// writeSimulatedResults produces an archive shaped like the one packed by getPackResultsCommand
+func writeSimulatedResults(localPath, podName string) error {
+	f, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	resultFolderName := strings.TrimSuffix(resultsPath, "/")
+	files := map[string]string{
+		"index.html":      "<html><body><h1>Simulated report for " + podName + "</h1></body></html>\n",
+		"statistics.json": "{}\n",
+	}
+	for name, content := range files {
+		hdr := &tar.Header{
+			Name:    path.Join("jmeter", resultFolderName, name),
+			Mode:    0644,
+			Size:    int64(len(content)),
+			ModTime: time.Now(),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
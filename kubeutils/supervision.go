@@ -0,0 +1,118 @@
+package kubeutils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The run wrapper records the pid of the run and, once run.sh returns, its exit code
+const (
+	runPidFile  = workDir + "/run.pid"
+	runExitFile = workDir + "/run.exit"
+)
+
+// shells report a child killed by signal N as exit code 128+N
+const signalExitCodeBase = 128
+
+// runStartGrace is how long the wrapper may take to write its pid file once a run was started.
+// Job pods pick up the trigger file within a second, exec'd runs write it right away.
+const runStartGrace = 30 * time.Second
+
+type runProcessState uint
+
+const (
+	runNotStarted runProcessState = iota
+	runRunning
+	runExited
+	// runKilled is a wrapper that disappeared without writing an exit code, or a run.sh killed by a signal
+	runKilled
+)
+
+// runProcess is the state of a run as the wrapper left it in the pod
+type runProcess struct {
+	state    runProcessState
+	exitCode int
+}
+
+func (p runProcess) String() string {
+	switch p.state {
+	case runRunning:
+		return "running"
+	case runExited:
+		return fmt.Sprintf("exited(%d)", p.exitCode)
+	case runKilled:
+		if p.exitCode > signalExitCodeBase {
+			return fmt.Sprintf("killed(signal %d)", p.exitCode-signalExitCodeBase)
+		}
+		return "killed"
+	default:
+		return "not started"
+	}
+}
+
+// getSupervisedRunScript runs run.sh and writes the pid and exit code files around it
+func getSupervisedRunScript() string {
+	return fmt.Sprintf("echo $$ > %s; sh ./run.sh > /dev/null 2>&1; echo $? > %s", runPidFile, runExitFile)
+}
+
+// getCheckRunStateCommand prints "running", "exited <code>", "killed" or "not-started".
+// The process state is read from /proc: the pod's main process does not reap orphans,
+// so a killed wrapper stays around as a zombie that kill -0 still reports alive.
+func getCheckRunStateCommand() string {
+	return fmt.Sprintf(
+		"if [ -f %s ]; then echo \"exited $(cat %s)\"; "+
+			"elif [ -f %s ]; then "+
+			"s=$(cut -d' ' -f3 /proc/$(cat %s)/stat 2>/dev/null); "+
+			"if [ -n \"$s\" ] && [ \"$s\" != Z ]; then echo running; else echo killed; fi; "+
+			"else echo not-started; fi",
+		runExitFile, runExitFile, runPidFile, runPidFile)
+}
+
+// runStarts remembers when runs were started, so a run that never started is told apart from one about to
+type runStarts struct {
+	mu    sync.Mutex
+	byPod map[string]time.Time
+}
+
+func (r *runStarts) set(podName string, started time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.byPod == nil {
+		r.byPod = make(map[string]time.Time)
+	}
+	r.byPod[podName] = started
+}
+
+// startingUp tells if the run of podName was started less than runStartGrace ago
+func (r *runStarts) startingUp(podName string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	started, ok := r.byPod[podName]
+	return ok && now.Sub(started) < runStartGrace
+}
+
+func parseRunState(out string) (runProcess, error) {
+	state, code, _ := strings.Cut(strings.TrimSpace(out), " ")
+	switch state {
+	case "running":
+		return runProcess{state: runRunning}, nil
+	case "killed":
+		return runProcess{state: runKilled}, nil
+	case "not-started":
+		return runProcess{state: runNotStarted}, nil
+	case "exited":
+		exitCode, err := strconv.Atoi(strings.TrimSpace(code))
+		if err != nil {
+			return runProcess{}, fmt.Errorf("unexpected exit code %q", code)
+		}
+		if exitCode > signalExitCodeBase {
+			return runProcess{state: runKilled, exitCode: exitCode}, nil
+		}
+		return runProcess{state: runExited, exitCode: exitCode}, nil
+	}
+
+	return runProcess{}, fmt.Errorf("unexpected run state %q", out)
+}
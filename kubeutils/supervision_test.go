@@ -0,0 +1,64 @@
+package kubeutils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRunState(t *testing.T) {
+	tests := []struct {
+		out     string
+		want    runProcess
+		text    string
+		wantErr bool
+	}{
+		{out: "running\n", want: runProcess{state: runRunning}, text: "running"},
+		{out: "not-started", want: runProcess{state: runNotStarted}, text: "not started"},
+		{out: "killed", want: runProcess{state: runKilled}, text: "killed"},
+		{out: "exited 0\n", want: runProcess{state: runExited}, text: "exited(0)"},
+		{out: "exited 1", want: runProcess{state: runExited, exitCode: 1}, text: "exited(1)"},
+		{out: "exited 128", want: runProcess{state: runExited, exitCode: 128}, text: "exited(128)"},
+		{out: "exited 137", want: runProcess{state: runKilled, exitCode: 137}, text: "killed(signal 9)"},
+		{out: "exited ", wantErr: true},
+		{out: "exited abc", wantErr: true},
+		{out: "", wantErr: true},
+		{out: "sh: cut: not found", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRunState(tt.out)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRunState(%q) err = %v, wantErr %v", tt.out, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseRunState(%q) = %+v, want %+v", tt.out, got, tt.want)
+		}
+		if got.String() != tt.text {
+			t.Errorf("parseRunState(%q) reads %q, want %q", tt.out, got.String(), tt.text)
+		}
+	}
+}
+
+func TestRunStartsStartingUp(t *testing.T) {
+	var starts runStarts
+	now := time.Now()
+
+	if starts.startingUp("load-0", now) {
+		t.Error("a run that was never started is starting up")
+	}
+
+	starts.set("load-0", now)
+	if !starts.startingUp("load-0", now.Add(runStartGrace-time.Second)) {
+		t.Error("a run within the grace period is not starting up")
+	}
+	if starts.startingUp("load-0", now.Add(runStartGrace)) {
+		t.Error("a run past the grace period is still starting up")
+	}
+	if starts.startingUp("load-1", now) {
+		t.Error("the start of another pod is used")
+	}
+}
@@ -1,8 +1,9 @@
 package kubeutils
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
-	"os/exec"
 	"sync"
 	"time"
 
@@ -11,15 +12,104 @@ import (
 	"k8s.io/client-go/rest"
 )
 
+// Backend is everything the orchestrator needs from an execution target.
+// Cluster is the Kubernetes implementation; the TUI depends only on this interface.
+type Backend interface {
+	Ping(ctx context.Context) (bool, error)
+	CreatePod(ctx context.Context, podName string) error
+	PreparePod(ctx context.Context, testInfo TestInfo, ch chan<- ActionDone) error
+	ExecInPod(ctx context.Context, podName, command string) (string, string, error)
+	UploadToPod(ctx context.Context, podName, localPath, remotePath string, ch chan<- ActionDone) (int64, error)
+	DownloadFromPod(ctx context.Context, podName, remotePath, localPath string, ch chan<- ActionDone) (int64, error)
+	KickstartTestForPod(ctx context.Context, testInfo TestInfo) error
+	// CheckProgress tells if the run in a pod is over and returns the log lines
+	// written since the previous check of the same pod
+	CheckProgress(ctx context.Context, testInfo TestInfo) (bool, string, error)
+	// TailResults returns the JTL lines written since the previous call for the same pod
+	TailResults(ctx context.Context, testInfo TestInfo) (string, error)
+	// GetPodResources reports CPU and memory usage of a pod against its limits
+	GetPodResources(ctx context.Context, podName string) (PodResources, error)
+	CancelRunForPod(ctx context.Context, testInfo TestInfo) error
+	ResetPodForNewRun(ctx context.Context, testInfo TestInfo) error
+	CollectResultsFromPod(ctx context.Context, testInfo TestInfo, ch chan<- ActionDone) error
+	DeletePod(ctx context.Context, podName string) error
+	// RollbackPod deletes a pod of a preparation that is given up or retried and waits until it is gone,
+	// so its name can be created again. A pod that was never created is not an error.
+	RollbackPod(ctx context.Context, podName string) error
+	DiscoverPods(ctx context.Context) ([]DiscoveredPod, error)
+	// PodTarget names where a pod runs, context/namespace for a cluster
+	PodTarget(podName string) string
+}
+
+// ClusterConfig holds the settings needed to connect to a cluster and create pods in it.
+type ClusterConfig struct {
+	KubeconfigPath  string
+	KubeCtxName     string
+	Namespace       string
+	PodPrefix       string
+	PodKeepAliveSec int
+	// Image used for pods, DefaultPodImage when empty
+	Image string
+	// JMeterHome points to JMeter inside a prebuilt Image. When set, in-pod installation is skipped
+	JMeterHome string
+	// JMeterVersion is installed into pods, DefaultJMeterVersion when empty
+	JMeterVersion string
+	// JMeterPlugins are installed into JMeter after it is unpacked
+	JMeterPlugins []JMeterPlugin
+	// ArtifactCacheDir holds JMeter, plugins and optionally a JDK that are uploaded into pods
+	// instead of being downloaded by them
+	ArtifactCacheDir string
+	// JDKArchive is a .tar.gz in ArtifactCacheDir installed instead of the apt JDK
+	JDKArchive string
+	// PodTemplatePath is a Pod manifest merged into every created pod
+	PodTemplatePath string
+	// UseJobs creates a Job per load generator instead of a bare pod
+	UseJobs bool
+	// JobTTLSec is how long finished Jobs are kept before the cluster removes them
+	JobTTLSec int
+	// ReadyTimeout is how long to wait for a created pod to become ready, DefaultReadyTimeout when zero
+	ReadyTimeout time.Duration
+	// Distributed makes <prefix>-0 a controller driving the other pods running jmeter-server
+	Distributed bool
+	// ListenerAddr is where pods reach the metrics receiver, host:port. When set a Backend
+	// Listener reporting there is added to every uploaded scenario.
+	ListenerAddr string
+	// QPS and Burst limit requests to the API server, the client-go defaults apply when zero
+	QPS   float32
+	Burst int
+	// Retry applies to exec and upload steps of pod preparation, DefaultRetryPolicy when zero
+	Retry RetryPolicy
+	// FirstPod is the index of the first pod this cluster holds when a session spans several targets
+	FirstPod int
+}
+
 type Cluster struct {
 	RestCfg         *rest.Config
-	Clientset       *kubernetes.Clientset
+	Clientset       kubernetes.Interface
 	Namespace       string
 	KubeCtxName     string
 	PodPrefix       string
 	PodsCache       *PodsCache
 	PodKeepAliveSec int
+	Image           string
+	UseJobs         bool
+	JobTTLSec       int
+	ReadyTimeout    time.Duration
+	Distributed     bool
+	Retry           RetryPolicy
+	ListenerAddr    string
 	Logger          slog.Logger
+
+	jmeter      jmeterInstall
+	podTemplate *v1.Pod
+	events      podEvents
+	logs        logOffsets
+	results     logOffsets
+	listenerURL string
+	firstPod    int
+	// controllerRun is what engines report in distributed mode
+	controllerRun controllerRunState
+	runStarts     runStarts
 }
 
 type TestInfo struct {
@@ -32,16 +122,64 @@ type ActionDone struct {
 	PodName  string
 	Name     string
 	Duration time.Duration
+	// Bytes transferred by the step, zero for steps that do not move files
+	Bytes int64
+	// Err is set when the step failed
+	Err error
+	// Attempts is how many times the step was tried
+	Attempts int
+	// Retrying means Err is not final, the step is tried again
+	Retrying bool
+}
+
+// PodNotReadyError explains why a pod did not become ready
+type PodNotReadyError struct {
+	PodName string
+	Phase   v1.PodPhase
+	Reason  string
+	Message string
+
+	fatal bool
+}
+
+func (e *PodNotReadyError) Error() string {
+	msg := fmt.Sprintf("pod %s is not ready: %s", e.PodName, e.Reason)
+	if e.Phase != "" && string(e.Phase) != e.Reason {
+		msg = fmt.Sprintf("pod %s is not ready: %s: %s", e.PodName, e.Phase, e.Reason)
+	}
+	if e.Message != "" {
+		msg += " (" + e.Message + ")"
+	}
+	return msg
+}
+
+// jmeterInstall describes where JMeter lives inside a pod and whether the orchestrator has to install it
+type jmeterInstall struct {
+	home     string
+	prebuilt bool
+	// version and plugins are only set when JMeter is installed by the orchestrator
+	version string
+	plugins []JMeterPlugin
+	// cache is set when artifacts are uploaded from a local directory
+	cache *artifactCache
 }
 
 type remoteCommand struct {
 	displayName string
 	command     string
+	// upload is copied into the pod before the command runs
+	upload *fileTransfer
+	// attempts overrides RetryPolicy.Attempts for this step when set, unless RetryPolicy.StepAttempts is off
+	attempts int
+	// retryExitCodes retries the step when the command exits non-zero, for idempotent
+	// steps that depend on the network or on locks
+	retryExitCodes bool
 }
 
-type localCommand struct {
+type fileTransfer struct {
 	displayName string
-	command     *exec.Cmd
+	localPath   string
+	remotePath  string
 }
 
 type PodsCache struct {
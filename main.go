@@ -3,9 +3,15 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log/slog"
+	"net"
 	"os"
+	"strings"
+	"terminalui/kubeutils"
+	"terminalui/metrics"
 	"terminalui/tui"
+	"time"
 )
 
 const (
@@ -20,10 +26,121 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "gc" {
+		runGarbageCollector(os.Args[2:])
+		return
+	}
+
 	customUpdateInterval := flag.Int("refresh", 3, "refresh rate for logs streaming")
 	customKeepAlive := flag.Int("keep-alive", 259200, "keep pods alive for N seconds")
+	image := flag.String("image", "", "container image for load generator pods. Defaults to "+kubeutils.DefaultPodImage)
+	jmeterHome := flag.String("jmeter-home", "", "JMeter home inside a prebuilt -image. When set, JMeter installation is skipped")
+	jmeterVersion := flag.String("jmeter-version", kubeutils.DefaultJMeterVersion, "JMeter version installed into pods")
+	plugins := flag.String("plugins", pluginsSpec(kubeutils.DefaultJMeterPlugins), "comma separated plugins installed into pods: "+
+		"Plugins Manager IDs with an optional version (jpgc-tst=2.6) or jar and zip URLs")
+	artifactCache := flag.String("artifact-cache", "", "local directory with JMeter, plugins and a JDK that are uploaded into pods instead of downloaded")
+	jdkArchive := flag.String("jdk-archive", "", "JDK .tar.gz in -artifact-cache installed instead of the apt JDK")
+	podTemplate := flag.String("pod-template", "", "path to a Pod manifest (YAML) used as a base for load generator pods")
+	useJobs := flag.Bool("jobs", false, "run load generators as Kubernetes Jobs that complete once results are collected")
+	jobTTL := flag.Int("job-ttl", 3600, "keep finished Jobs for N seconds (with -jobs)")
+	concurrency := flag.Int("concurrency", tui.DefaultConcurrency, "how many pods are prepared, collected or deleted at once")
+	kubeQPS := flag.Float64("kube-qps", 0, "requests per second to the API server, 0 keeps the client-go default of 5")
+	kubeBurst := flag.Int("kube-burst", 0, "request burst to the API server, 0 keeps the client-go default of 10")
+	retries := flag.Int("retries", kubeutils.DefaultRetryAttempts, "how many times a pod preparation step is tried when it fails on a transient error. "+
+		"When not set, apt steps get more attempts")
+	readyTimeout := flag.Duration("ready-timeout", kubeutils.DefaultReadyTimeout, "how long to wait for a pod to become ready")
+	distributed := flag.Bool("distributed", false, "run <prefix>-0 as a JMeter controller driving the other pods as remote engines")
+	listen := flag.String("listen-metrics", "", "address to receive JMeter Backend Listener (InfluxDB line protocol) metrics on, e.g. ':8086'")
+	advertise := flag.String("advertise-metrics", "", "ip:port or host:port pods reach -listen-metrics at. An IP is published in-cluster as the <prefix>-metrics Service")
+	targets := flag.String("targets", "", "spread pods over several clusters, e.g. 'eu-west/load=3,us-east/load=2' (context/namespace=pods). "+
+		"Overrides namespace, context and amount of pods of the config form")
+	reattach := flag.Bool("reattach", false, "pick up pods of an earlier session by prefix instead of creating new ones")
+	kubeconfig := flag.String("kubeconfig", "", "path to the kubeconfig file. Defaults to $KUBECONFIG (merged) or ~/.kube/config")
+	simulate := flag.Bool("simulate", false, "use an in-memory simulated cluster instead of a real one")
+	simRunDuration := flag.Duration("sim-run-duration", 45*time.Second, "how long a simulated run takes")
+	simPodStartup := flag.Duration("sim-pod-startup", 2*time.Second, "how long a simulated pod takes to start")
+	simStep := flag.Duration("sim-step", time.Second, "how long each simulated setup step takes")
+	simSeed := flag.Int64("sim-seed", 1, "seed for simulated logs")
+	simFailures := flag.String("sim-failures", "", "inject failures into simulated pods, e.g. 'slow=0,vanish=2'. "+
+		"Kinds: slow, image-pull, unschedulable, prepare-error, exec-error, vanish, stall, no-results, flaky")
 	flag.Parse()
 
+	if *jmeterHome != "" && *image == "" {
+		fmt.Println("-jmeter-home requires -image with JMeter preinstalled")
+		os.Exit(1)
+	}
+
+	if *jdkArchive != "" && *artifactCache == "" {
+		fmt.Println("-jdk-archive requires -artifact-cache")
+		os.Exit(1)
+	}
+	if *artifactCache != "" && *jdkArchive == "" && *image == "" {
+		fmt.Println("-artifact-cache requires -jdk-archive, or an -image that has java: pods without egress can not install one with apt")
+		os.Exit(1)
+	}
+
+	if *podTemplate != "" {
+		// fail before the forms rather than when connecting to the cluster
+		if _, err := kubeutils.LoadPodTemplate(*podTemplate); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if *listen != "" && *advertise == "" && !*simulate {
+		fmt.Println("-listen-metrics requires -advertise-metrics, an address pods can reach this machine at")
+		os.Exit(1)
+	}
+	if *listen != "" && *distributed {
+		// every engine would report as <prefix>-0 and only the last window would be shown
+		fmt.Println("-listen-metrics can not be combined with -distributed, engines can not be told apart")
+		os.Exit(1)
+	}
+
+	clusterTargets, err := kubeutils.ParseClusterTargets(*targets)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(clusterTargets) > 0 && *distributed {
+		fmt.Println("-distributed can not be combined with -targets, engines must share the controller's cluster")
+		os.Exit(1)
+	}
+	if kubeutils.TotalPods(clusterTargets) > 99 {
+		fmt.Println("-targets asks for more than 99 pods")
+		os.Exit(1)
+	}
+
+	if *concurrency < 1 {
+		fmt.Println("-concurrency must be at least 1")
+		os.Exit(1)
+	}
+
+	if *retries < 1 {
+		fmt.Println("-retries must be at least 1")
+		os.Exit(1)
+	}
+	retryPolicy := kubeutils.DefaultRetryPolicy()
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "retries" {
+			// an explicit -retries applies to every step, apt included
+			retryPolicy.Attempts = *retries
+			retryPolicy.StepAttempts = false
+		}
+	})
+
+	jmeterPlugins, err := kubeutils.ParseJMeterPlugins(*plugins)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	failures, err := kubeutils.ParseSimulatorFailures(*simFailures)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
 	if customUpdateInterval != nil {
 		updateInterval = *customUpdateInterval
 	} else {
@@ -44,5 +161,65 @@ func main() {
 	}
 
 	logger := slog.New(slog.NewTextHandler(logFile, &slog.HandlerOptions{}))
-	tui.DisplayUI(ctx, logger, updateInterval, keepAlive)
+
+	var listener *metrics.Receiver
+	listenerAddr := *advertise
+	if *listen != "" {
+		listener = metrics.NewReceiver()
+		if err := listener.Start(*listen); err != nil {
+			fmt.Println("failed to start metrics receiver:", err)
+			os.Exit(1)
+		}
+		defer listener.Close()
+
+		// simulated pods run in this process
+		if *simulate && listenerAddr == "" {
+			_, port, _ := net.SplitHostPort(listener.Addr())
+			listenerAddr = net.JoinHostPort("127.0.0.1", port)
+		}
+	}
+
+	settings := tui.AppSettings{
+		UpdateIntervalSec: updateInterval,
+		Concurrency:       *concurrency,
+		Cluster: kubeutils.ClusterConfig{
+			KubeconfigPath:   *kubeconfig,
+			PodKeepAliveSec:  keepAlive,
+			Image:            *image,
+			JMeterHome:       *jmeterHome,
+			JMeterVersion:    *jmeterVersion,
+			JMeterPlugins:    jmeterPlugins,
+			ArtifactCacheDir: *artifactCache,
+			JDKArchive:       *jdkArchive,
+			PodTemplatePath:  *podTemplate,
+			UseJobs:          *useJobs,
+			JobTTLSec:        *jobTTL,
+			ReadyTimeout:     *readyTimeout,
+			Retry:            retryPolicy,
+			QPS:              float32(*kubeQPS),
+			Burst:            *kubeBurst,
+			Distributed:      *distributed,
+			ListenerAddr:     listenerAddr,
+		},
+		Simulate: *simulate,
+		Reattach: *reattach,
+		Listener: listener,
+		Targets:  clusterTargets,
+		Simulator: kubeutils.SimulatorConfig{
+			PodStartup:   *simPodStartup,
+			StepDuration: *simStep,
+			RunDuration:  *simRunDuration,
+			Seed:         *simSeed,
+			Failures:     failures,
+		},
+	}
+	tui.DisplayUI(ctx, logger, settings)
+}
+
+func pluginsSpec(plugins []kubeutils.JMeterPlugin) string {
+	specs := make([]string, len(plugins))
+	for i, plugin := range plugins {
+		specs[i] = plugin.String()
+	}
+	return strings.Join(specs, ",")
 }
@@ -0,0 +1,95 @@
+package metrics
+
+import "sort"
+
+// values below this are counted exactly, larger ones keep 3 significant digits (under 1% error)
+const exactBucketLimit = 1000
+
+// Histogram counts latencies in milliseconds. Bucket boundaries are the same for every
+// histogram, so histograms of different pods merge by adding counts.
+type Histogram struct {
+	counts map[int]int64
+	total  int64
+	max    int64
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{counts: make(map[int]int64)}
+}
+
+func bucketOf(ms int64) int {
+	if ms < exactBucketLimit {
+		return int(max(ms, 0))
+	}
+
+	exp := 0
+	for ms >= exactBucketLimit {
+		ms /= 10
+		exp++
+	}
+	return exactBucketLimit + (exp-1)*900 + int(ms-100)
+}
+
+// bucketValue is the lower bound of a bucket
+func bucketValue(bucket int) int64 {
+	if bucket < exactBucketLimit {
+		return int64(bucket)
+	}
+
+	exp := (bucket-exactBucketLimit)/900 + 1
+	value := int64((bucket-exactBucketLimit)%900 + 100)
+	for range exp {
+		value *= 10
+	}
+	return value
+}
+
+func (h *Histogram) Record(ms int64) {
+	h.counts[bucketOf(ms)]++
+	h.total++
+	h.max = max(h.max, ms)
+}
+
+// Merge adds the counts of other to h
+func (h *Histogram) Merge(other *Histogram) {
+	for bucket, count := range other.counts {
+		h.counts[bucket] += count
+	}
+	h.total += other.total
+	h.max = max(h.max, other.max)
+}
+
+func (h *Histogram) Count() int64 {
+	return h.total
+}
+
+func (h *Histogram) Max() int64 {
+	return h.max
+}
+
+// Quantile returns the latency q (0..1) of the samples are at or below
+func (h *Histogram) Quantile(q float64) int64 {
+	if h.total == 0 {
+		return 0
+	}
+
+	buckets := make([]int, 0, len(h.counts))
+	for bucket := range h.counts {
+		buckets = append(buckets, bucket)
+	}
+	sort.Ints(buckets)
+
+	rank := int64(q * float64(h.total))
+	if rank >= h.total {
+		rank = h.total - 1
+	}
+
+	var seen int64
+	for _, bucket := range buckets {
+		seen += h.counts[bucket]
+		if seen > rank {
+			return min(bucketValue(bucket), h.max)
+		}
+	}
+	return h.max
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import "testing"
+
+func TestBucketOf(t *testing.T) {
+	tests := []struct {
+		ms     int64
+		bucket int
+		lower  int64
+	}{
+		{ms: -5, bucket: 0, lower: 0},
+		{ms: 0, bucket: 0, lower: 0},
+		{ms: 999, bucket: 999, lower: 999},
+		{ms: 1000, bucket: 1000, lower: 1000},
+		{ms: 1009, bucket: 1000, lower: 1000},
+		{ms: 1010, bucket: 1001, lower: 1010},
+		{ms: 9999, bucket: 1899, lower: 9990},
+		{ms: 10000, bucket: 1900, lower: 10000},
+		{ms: 10099, bucket: 1900, lower: 10000},
+		{ms: 123456, bucket: 2823, lower: 123000},
+	}
+
+	for _, tt := range tests {
+		bucket := bucketOf(tt.ms)
+		if bucket != tt.bucket {
+			t.Errorf("bucketOf(%d) = %d, want %d", tt.ms, bucket, tt.bucket)
+		}
+		if lower := bucketValue(bucket); lower != tt.lower {
+			t.Errorf("bucketValue(bucketOf(%d)) = %d, want %d", tt.ms, lower, tt.lower)
+		}
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	uniform := NewHistogram()
+	for ms := int64(1); ms <= 100; ms++ {
+		uniform.Record(ms)
+	}
+
+	skewed := NewHistogram()
+	for range 99 {
+		skewed.Record(10)
+	}
+	skewed.Record(12345)
+
+	tests := []struct {
+		name      string
+		histogram *Histogram
+		q         float64
+		want      int64
+	}{
+		{name: "empty", histogram: NewHistogram(), q: 0.5, want: 0},
+		{name: "min", histogram: uniform, q: 0, want: 1},
+		{name: "median", histogram: uniform, q: 0.5, want: 51},
+		{name: "p90", histogram: uniform, q: 0.9, want: 91},
+		{name: "p99", histogram: uniform, q: 0.99, want: 100},
+		{name: "max", histogram: uniform, q: 1, want: 100},
+		{name: "below the outlier", histogram: skewed, q: 0.98, want: 10},
+		{name: "outlier rounded down to its bucket", histogram: skewed, q: 0.99, want: 12300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.histogram.Quantile(tt.q); got != tt.want {
+				t.Errorf("Quantile(%v) = %d, want %d", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	a, b := NewHistogram(), NewHistogram()
+	for ms := int64(1); ms <= 50; ms++ {
+		a.Record(ms)
+	}
+	for ms := int64(51); ms <= 100; ms++ {
+		b.Record(ms)
+	}
+	a.Merge(b)
+
+	if a.Count() != 100 || a.Max() != 100 {
+		t.Fatalf("count %d, max %d, want 100 and 100", a.Count(), a.Max())
+	}
+	if p50 := a.Quantile(0.5); p50 != 51 {
+		t.Errorf("merged median = %d, want 51", p50)
+	}
+}
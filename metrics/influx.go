@@ -0,0 +1,256 @@
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ListenerSendInterval is how often JMeter's InfluxdbBackendListenerClient sends a window of metrics
+const ListenerSendInterval = 5 * time.Second
+
+// InfluxdbBackendListenerClient reports all samplers together under this transaction,
+// thread counts under "internal"
+const (
+	allTransaction      = "all"
+	internalTransaction = "internal"
+)
+
+// ListenerStats is the latest window of Backend Listener metrics of one application
+type ListenerStats struct {
+	Application string
+	// Throughput is in samples per second over the latest window
+	Throughput    float64
+	AvgMs         float64
+	MaxMs         float64
+	Pct90Ms       float64
+	Pct95Ms       float64
+	Pct99Ms       float64
+	ErrorPct      float64
+	ActiveThreads int
+	Updated       time.Time
+}
+
+// Receiver accepts InfluxDB line protocol writes from JMeter Backend Listeners, so
+// live metrics do not need an InfluxDB of their own
+type Receiver struct {
+	mu       sync.Mutex
+	apps     map[string]*ListenerStats
+	listener net.Listener
+	server   *http.Server
+}
+
+func NewReceiver() *Receiver {
+	return &Receiver{apps: make(map[string]*ListenerStats)}
+}
+
+// Start listens on addr, e.g. ":8086", and serves writes in the background
+func (r *Receiver) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	r.listener = listener
+	r.server = &http.Server{Handler: r, ReadHeaderTimeout: 10 * time.Second}
+	go r.server.Serve(listener)
+	return nil
+}
+
+// Addr is the address the receiver listens on
+func (r *Receiver) Addr() string {
+	if r.listener == nil {
+		return ""
+	}
+	return r.listener.Addr().String()
+}
+
+func (r *Receiver) Close() error {
+	if r.server == nil {
+		return nil
+	}
+	return r.server.Close()
+}
+
+// ServeHTTP implements /write and /ping of the InfluxDB 1.x API
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.URL.Path == "/ping":
+		w.WriteHeader(http.StatusNoContent)
+	case req.URL.Path == "/write" && req.Method == http.MethodPost:
+		scanner := bufio.NewScanner(req.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if point, err := ParseLineProtocol(scanner.Text()); err == nil {
+				r.ingest(point)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (r *Receiver) ingest(p Point) {
+	app := p.Tags["application"]
+	transaction := p.Tags["transaction"]
+	if app == "" || (transaction != allTransaction && transaction != internalTransaction) {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.apps[app]
+	if stats == nil {
+		stats = &ListenerStats{Application: app}
+		r.apps[app] = stats
+	}
+	stats.Updated = time.Now()
+
+	if transaction == internalTransaction {
+		if threads, ok := p.Fields["meanAT"]; ok {
+			stats.ActiveThreads = int(threads)
+		}
+		return
+	}
+
+	if p.Tags["statut"] != "all" {
+		return
+	}
+
+	count := p.Fields["count"]
+	stats.Throughput = count / ListenerSendInterval.Seconds()
+	stats.AvgMs = p.Fields["avg"]
+	stats.MaxMs = p.Fields["max"]
+	stats.ErrorPct = 0
+	if count > 0 {
+		stats.ErrorPct = p.Fields["countError"] * 100 / count
+	}
+
+	for name, value := range p.Fields {
+		percentile, err := strconv.ParseFloat(strings.TrimPrefix(name, "pct"), 64)
+		if !strings.HasPrefix(name, "pct") || err != nil {
+			continue
+		}
+		switch percentile {
+		case 90:
+			stats.Pct90Ms = value
+		case 95:
+			stats.Pct95Ms = value
+		case 99:
+			stats.Pct99Ms = value
+		}
+	}
+}
+
+// Snapshot returns the latest stats of every application, sorted by name
+func (r *Receiver) Snapshot() []ListenerStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := make([]ListenerStats, 0, len(r.apps))
+	for _, stats := range r.apps {
+		snapshot = append(snapshot, *stats)
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		return snapshot[i].Application < snapshot[j].Application
+	})
+	return snapshot
+}
+
+// Reset forgets everything received, e.g. before a new run
+func (r *Receiver) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.apps = make(map[string]*ListenerStats)
+}
+
+// Point is a single line of InfluxDB line protocol. String fields are dropped, numbers kept as float64.
+type Point struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      map[string]float64
+}
+
+// ParseLineProtocol parses `measurement,tag=value field=1.5,count=3i 1700000000000000000`
+func ParseLineProtocol(line string) (Point, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return Point{}, errors.New("empty line")
+	}
+
+	sections := splitUnescaped(line, ' ', true)
+	if len(sections) < 2 {
+		return Point{}, errors.New("line has no fields")
+	}
+
+	p := Point{Tags: make(map[string]string), Fields: make(map[string]float64)}
+	series := splitUnescaped(sections[0], ',', false)
+	p.Measurement = unescape(series[0])
+	for _, tag := range series[1:] {
+		kv := splitUnescaped(tag, '=', false)
+		if len(kv) != 2 {
+			return Point{}, errors.New("invalid tag " + tag)
+		}
+		p.Tags[unescape(kv[0])] = unescape(kv[1])
+	}
+
+	for _, field := range splitUnescaped(sections[1], ',', true) {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			return Point{}, errors.New("invalid field " + field)
+		}
+		if strings.HasPrefix(value, "\"") {
+			continue
+		}
+		value = strings.TrimSuffix(strings.TrimSuffix(value, "i"), "u")
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		p.Fields[unescape(key)] = number
+	}
+
+	return p, nil
+}
+
+// splitUnescaped splits s on sep unless it is escaped with a backslash or, with quotes set, inside double quotes
+func splitUnescaped(s string, sep byte, quotes bool) []string {
+	var parts []string
+	start := 0
+	inQuotes := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case quotes && s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == sep && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLineProtocol(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    Point
+		wantErr bool
+	}{
+		{
+			name: "backend listener window",
+			line: "jmeter,application=shop-0,transaction=all,statut=all count=250i,countError=5i,avg=12.5,max=90,pct90.0=30 1700000000000000000",
+			want: Point{
+				Measurement: "jmeter",
+				Tags:        map[string]string{"application": "shop-0", "transaction": "all", "statut": "all"},
+				Fields:      map[string]float64{"count": 250, "countError": 5, "avg": 12.5, "max": 90, "pct90.0": 30},
+			},
+		},
+		{
+			name: "escaped spaces and commas",
+			line: `my\ measurement,application=load\ test\,eu,transaction=GET\ /cart field\ one=1.5,count=3u`,
+			want: Point{
+				Measurement: "my measurement",
+				Tags:        map[string]string{"application": "load test,eu", "transaction": "GET /cart"},
+				Fields:      map[string]float64{"field one": 1.5, "count": 3},
+			},
+		},
+		{
+			name: "string fields are dropped",
+			line: `events,application=shop text="started, 10 users",count=1i`,
+			want: Point{
+				Measurement: "events",
+				Tags:        map[string]string{"application": "shop"},
+				Fields:      map[string]float64{"count": 1},
+			},
+		},
+		{name: "empty", line: "   ", wantErr: true},
+		{name: "comment", line: "# written by jmeter", wantErr: true},
+		{name: "no fields", line: "jmeter,application=shop", wantErr: true},
+		{name: "invalid tag", line: "jmeter,application count=1i", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLineProtocol(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Measurement != tt.want.Measurement || !maps.Equal(got.Tags, tt.want.Tags) || !maps.Equal(got.Fields, tt.want.Fields) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReceiverWrite(t *testing.T) {
+	receiver := NewReceiver()
+	body := strings.Join([]string{
+		"jmeter,application=shop-0,transaction=all,statut=all count=50i,countError=5i,avg=20,max=80,pct90.0=40,pct95.0=60,pct99.0=75",
+		"jmeter,application=shop-0,transaction=all,statut=ok count=45i,avg=18",
+		"jmeter,application=shop-0,transaction=internal minAT=2i,maxAT=4i,meanAT=3i",
+		"jmeter,application=shop-0,transaction=home,statut=all count=50i,avg=1000",
+		"not a point",
+	}, "\n")
+
+	rec := httptest.NewRecorder()
+	receiver.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/write?db=jmeter", strings.NewReader(body)))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	snapshot := receiver.Snapshot()
+	if len(snapshot) != 1 {
+		t.Fatalf("got %d applications, want 1", len(snapshot))
+	}
+	got := snapshot[0]
+	if got.Updated.IsZero() {
+		t.Error("update time is not set")
+	}
+	got.Updated = time.Time{}
+	want := ListenerStats{
+		Application: "shop-0", Throughput: 10, AvgMs: 20, MaxMs: 80,
+		Pct90Ms: 40, Pct95Ms: 60, Pct99Ms: 75, ErrorPct: 10, ActiveThreads: 3,
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultJTLColumns is the CSV layout JMeter writes unless jmeter.save.saveservice.* is changed.
+// It is used until a header line is seen, e.g. when reading starts in the middle of a file.
+var defaultJTLColumns = []string{
+	"timeStamp", "elapsed", "label", "responseCode", "responseMessage", "threadName", "dataType",
+	"success", "failureMessage", "bytes", "sentBytes", "grpThreads", "allThreads", "URL", "Latency", "IdleTime", "Connect",
+}
+
+// ResultsStats aggregates the samples of a JTL file
+type ResultsStats struct {
+	All     *Histogram
+	ByLabel map[string]*Histogram
+	// ErrorsByCode counts failed samples by response code
+	ErrorsByCode map[string]int64
+	Errors       int64
+	// ActiveThreads is allThreads of the latest sample
+	ActiveThreads int
+
+	columns map[string]int
+	lastTS  int64
+}
+
+func NewResultsStats() *ResultsStats {
+	s := &ResultsStats{
+		All:          NewHistogram(),
+		ByLabel:      make(map[string]*Histogram),
+		ErrorsByCode: make(map[string]int64),
+	}
+	s.setColumns(defaultJTLColumns)
+	return s
+}
+
+func (s *ResultsStats) setColumns(header []string) {
+	s.columns = make(map[string]int, len(header))
+	for i, name := range header {
+		s.columns[name] = i
+	}
+}
+
+// Ingest parses complete CSV lines of a JTL. Lines that do not parse, e.g. a line cut when
+// reading skipped ahead, are ignored.
+func (s *ResultsStats) Ingest(lines string) {
+	reader := csv.NewReader(strings.NewReader(lines))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			continue
+		}
+
+		if len(record) > 0 && record[0] == "timeStamp" {
+			s.setColumns(record)
+			continue
+		}
+		s.add(record)
+	}
+}
+
+func (s *ResultsStats) field(record []string, name string) (string, bool) {
+	idx, ok := s.columns[name]
+	if !ok || idx >= len(record) {
+		return "", false
+	}
+	return record[idx], true
+}
+
+func (s *ResultsStats) add(record []string) {
+	elapsedField, ok := s.field(record, "elapsed")
+	if !ok {
+		return
+	}
+	elapsed, err := strconv.ParseInt(elapsedField, 10, 64)
+	if err != nil {
+		return
+	}
+
+	label, _ := s.field(record, "label")
+	histogram := s.ByLabel[label]
+	if histogram == nil {
+		histogram = NewHistogram()
+		s.ByLabel[label] = histogram
+	}
+	histogram.Record(elapsed)
+	s.All.Record(elapsed)
+
+	if success, ok := s.field(record, "success"); ok && success != "true" {
+		code, _ := s.field(record, "responseCode")
+		s.ErrorsByCode[code]++
+		s.Errors++
+	}
+
+	tsField, _ := s.field(record, "timeStamp")
+	ts, _ := strconv.ParseInt(tsField, 10, 64)
+	if threadsField, ok := s.field(record, "allThreads"); ok && ts >= s.lastTS {
+		if threads, err := strconv.Atoi(threadsField); err == nil {
+			s.ActiveThreads = threads
+			s.lastTS = ts
+		}
+	}
+}
+
+// Merge adds the samples of other to s, active threads add up as pods run in parallel
+func (s *ResultsStats) Merge(other *ResultsStats) {
+	s.All.Merge(other.All)
+	for label, histogram := range other.ByLabel {
+		if s.ByLabel[label] == nil {
+			s.ByLabel[label] = NewHistogram()
+		}
+		s.ByLabel[label].Merge(histogram)
+	}
+	for code, count := range other.ErrorsByCode {
+		s.ErrorsByCode[code] += count
+	}
+	s.Errors += other.Errors
+	s.ActiveThreads += other.ActiveThreads
+}
+
+// Labels returns the sampler labels sorted by name
+func (s *ResultsStats) Labels() []string {
+	labels := make([]string, 0, len(s.ByLabel))
+	for label := range s.ByLabel {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestResultsStatsIngest(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   string
+		count   int64
+		labels  []string
+		errors  map[string]int64
+		threads int
+	}{
+		{
+			name: "default columns",
+			lines: "1700000000000,120,home,200,OK,Group 1-1,text,true,,512,100,1,1,https://host/,110,0,5\n" +
+				"1700000000100,340,login,500,Internal Server Error,Group 1-2,text,false,boom,128,100,2,2,https://host/login,330,0,4\n",
+			count:   2,
+			labels:  []string{"home", "login"},
+			errors:  map[string]int64{"500": 1},
+			threads: 2,
+		},
+		{
+			name: "header",
+			lines: "timeStamp,label,elapsed,success,responseCode,allThreads\n" +
+				"1700000000000,home,120,true,200,3\n" +
+				"1700000000100,home,80,false,404,4\n",
+			count:   2,
+			labels:  []string{"home"},
+			errors:  map[string]int64{"404": 1},
+			threads: 4,
+		},
+		{
+			name: "quoted commas",
+			lines: "timeStamp,elapsed,label,responseCode,responseMessage,success,failureMessage,allThreads\n" +
+				`1700000000000,250,"search, page 2",503,"Service Unavailable, retry",false,"timeout, after 3s",5` + "\n",
+			count:   1,
+			labels:  []string{"search, page 2"},
+			errors:  map[string]int64{"503": 1},
+			threads: 5,
+		},
+		{
+			name: "older and unparsable lines",
+			lines: "0000,12,home,200\n" +
+				"1700000000100,90,home,200,OK,Group 1-1,text,true,,512,100,1,7,https://host/,80,0,5\n" +
+				"1700000000000,90,home,200,OK,Group 1-1,text,true,,512,100,1,6,https://host/,80,0,5\n" +
+				"17000000,abc\n",
+			count:   3,
+			labels:  []string{"home"},
+			errors:  map[string]int64{},
+			threads: 7,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := NewResultsStats()
+			stats.Ingest(tt.lines)
+
+			if stats.All.Count() != tt.count {
+				t.Errorf("got %d samples, want %d", stats.All.Count(), tt.count)
+			}
+			if labels := stats.Labels(); !slices.Equal(labels, tt.labels) {
+				t.Errorf("got labels %q, want %q", labels, tt.labels)
+			}
+			if !maps.Equal(stats.ErrorsByCode, tt.errors) {
+				t.Errorf("got errors %v, want %v", stats.ErrorsByCode, tt.errors)
+			}
+			if stats.ActiveThreads != tt.threads {
+				t.Errorf("got %d active threads, want %d", stats.ActiveThreads, tt.threads)
+			}
+		})
+	}
+}
+
+func TestResultsStatsHeaderAcrossChunks(t *testing.T) {
+	stats := NewResultsStats()
+	stats.Ingest("timeStamp,label,elapsed,success,responseCode\n")
+	stats.Ingest("1700000000000,home,120,true,200\n")
+
+	if stats.ByLabel["home"] == nil || stats.ByLabel["home"].Max() != 120 {
+		t.Errorf("the header of an earlier chunk was not applied: %v", stats.Labels())
+	}
+}
+
+func TestResultsStatsMerge(t *testing.T) {
+	a, b := NewResultsStats(), NewResultsStats()
+	a.Ingest("timeStamp,elapsed,label,responseCode,success,allThreads\n1,100,home,200,true,2\n")
+	b.Ingest("timeStamp,elapsed,label,responseCode,success,allThreads\n1,300,login,500,false,3\n")
+	a.Merge(b)
+
+	if a.All.Count() != 2 || a.Errors != 1 || a.ErrorsByCode["500"] != 1 || a.ActiveThreads != 5 {
+		t.Errorf("got %d samples, %d errors %v, %d threads", a.All.Count(), a.Errors, a.ErrorsByCode, a.ActiveThreads)
+	}
+	if labels := a.Labels(); !slices.Equal(labels, []string{"home", "login"}) {
+		t.Errorf("got labels %q", labels)
+	}
+}
@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// only the latest interval summaries are kept for every pod
+const maxSeriesPoints = 720
+
+// summaryPattern matches lines of JMeter's Summariser, e.g.
+//
+//	summary +   1234 in 00:00:30 =   41.1/s Avg:   123 Min:    10 Max:  2345 Err:     0 (0.00%) Active: 10 Started: 10 Finished: 0
+//	summary =   5678 in 00:02:00 =   47.3/s Avg:   110 Min:     8 Max:  3000 Err:    12 (0.21%)
+var summaryPattern = regexp.MustCompile(
+	`\S+ ([+=])\s+(\d+) in (\d+):(\d\d):(\d\d) =\s+([\d.,]+)/s Avg:\s+(\d+) Min:\s+(\d+) Max:\s+(\d+) Err:\s+(\d+) \(([\d.,]+)%\)(?: Active: (\d+))?`)
+
+// Summary is a single summariser line
+type Summary struct {
+	// Cumulative is a "summary =" line covering the whole run, otherwise it covers the latest interval
+	Cumulative bool
+	Samples    int64
+	Elapsed    time.Duration
+	// Throughput is in samples per second
+	Throughput float64
+	AvgMs      int64
+	MinMs      int64
+	MaxMs      int64
+	Errors     int64
+	// Active threads, only reported on interval lines
+	Active int
+}
+
+// ErrorPct is the share of failed samples in percent
+func (s Summary) ErrorPct() float64 {
+	if s.Samples == 0 {
+		return 0
+	}
+	return float64(s.Errors) * 100 / float64(s.Samples)
+}
+
+// ParseSummary parses a summariser line, ok is false for any other line
+func ParseSummary(line string) (Summary, bool) {
+	m := summaryPattern.FindStringSubmatch(line)
+	if m == nil {
+		return Summary{}, false
+	}
+
+	atoi := func(s string) int64 {
+		n, _ := strconv.ParseInt(s, 10, 64)
+		return n
+	}
+	// the decimal separator follows the locale of the JVM
+	atof := func(s string) float64 {
+		f, _ := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+		return f
+	}
+
+	s := Summary{
+		Cumulative: m[1] == "=",
+		Samples:    atoi(m[2]),
+		Elapsed:    time.Duration(atoi(m[3])*3600+atoi(m[4])*60+atoi(m[5])) * time.Second,
+		Throughput: atof(m[6]),
+		AvgMs:      atoi(m[7]),
+		MinMs:      atoi(m[8]),
+		MaxMs:      atoi(m[9]),
+		Errors:     atoi(m[10]),
+	}
+	if m[12] != "" {
+		s.Active = int(atoi(m[12]))
+	}
+
+	return s, true
+}
+
+// PodSeries is the summariser output of one pod
+type PodSeries struct {
+	// Intervals are "summary +" lines, oldest first
+	Intervals []Summary
+	Total     Summary
+	hasTotal  bool
+}
+
+// Ingest parses the summariser lines found in a chunk of log
+func (p *PodSeries) Ingest(logs string) {
+	for _, line := range strings.Split(logs, "\n") {
+		s, ok := ParseSummary(line)
+		if !ok {
+			continue
+		}
+
+		if s.Cumulative {
+			p.Total = s
+			p.hasTotal = true
+			continue
+		}
+
+		p.Intervals = append(p.Intervals, s)
+		if len(p.Intervals) > maxSeriesPoints {
+			p.Intervals = p.Intervals[len(p.Intervals)-maxSeriesPoints:]
+		}
+	}
+}
+
+// Reset forgets everything ingested so far, e.g. before a new run
+func (p *PodSeries) Reset() {
+	*p = PodSeries{}
+}
+
+// Current is the latest interval summary
+func (p *PodSeries) Current() (Summary, bool) {
+	if len(p.Intervals) == 0 {
+		return Summary{}, false
+	}
+	return p.Intervals[len(p.Intervals)-1], true
+}
+
+// Cumulative is the latest summary of the whole run
+func (p *PodSeries) Cumulative() (Summary, bool) {
+	return p.Total, p.hasTotal
+}
+
+// Aggregate sums up summaries of pods running in parallel: samples, errors and
+// throughput add up, averages are weighted by samples
+func Aggregate(summaries []Summary) Summary {
+	var total Summary
+	var weightedAvg int64
+	for i, s := range summaries {
+		total.Samples += s.Samples
+		total.Errors += s.Errors
+		total.Throughput += s.Throughput
+		total.Active += s.Active
+		total.Elapsed = max(total.Elapsed, s.Elapsed)
+		total.Cumulative = s.Cumulative
+		weightedAvg += s.AvgMs * s.Samples
+
+		if i == 0 || s.MinMs < total.MinMs {
+			total.MinMs = s.MinMs
+		}
+		total.MaxMs = max(total.MaxMs, s.MaxMs)
+	}
+
+	if total.Samples > 0 {
+		total.AvgMs = weightedAvg / total.Samples
+	}
+	return total
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want Summary
+		ok   bool
+	}{
+		{
+			name: "interval",
+			line: "summary +   1234 in 00:00:30 =   41.1/s Avg:   123 Min:    10 Max:  2345 Err:     5 (0.41%) Active: 10 Started: 10 Finished: 0",
+			want: Summary{Samples: 1234, Elapsed: 30 * time.Second, Throughput: 41.1, AvgMs: 123, MinMs: 10, MaxMs: 2345, Errors: 5, Active: 10},
+			ok:   true,
+		},
+		{
+			name: "cumulative",
+			line: "summary =   5678 in 01:02:03 =   47.3/s Avg:   110 Min:     8 Max:  3000 Err:    12 (0.21%)",
+			want: Summary{Cumulative: true, Samples: 5678, Elapsed: time.Hour + 2*time.Minute + 3*time.Second,
+				Throughput: 47.3, AvgMs: 110, MinMs: 8, MaxMs: 3000, Errors: 12},
+			ok: true,
+		},
+		{
+			name: "comma decimal separator",
+			line: "summary +    250 in 00:00:05 =   49,8/s Avg:    20 Min:     1 Max:    90 Err:     0 (0,00%) Active: 3 Started: 3 Finished: 0",
+			want: Summary{Samples: 250, Elapsed: 5 * time.Second, Throughput: 49.8, AvgMs: 20, MinMs: 1, MaxMs: 90, Active: 3},
+			ok:   true,
+		},
+		{
+			name: "log prefix",
+			line: "2024-05-01 10:00:00,123 INFO o.a.j.r.Summariser: summary +     10 in 00:00:01 =   10.0/s Avg:     5 Min:     1 Max:     9 Err:     1 (10.00%) Active: 1 Started: 1 Finished: 0",
+			want: Summary{Samples: 10, Elapsed: time.Second, Throughput: 10, AvgMs: 5, MinMs: 1, MaxMs: 9, Errors: 1, Active: 1},
+			ok:   true,
+		},
+		{
+			name: "other line",
+			line: "Starting standalone test @ 2024 May 1 10:00:00 UTC",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseSummary(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodSeriesIngest(t *testing.T) {
+	var series PodSeries
+	series.Ingest("summary +    100 in 00:00:30 =    3.3/s Avg:    10 Min:     1 Max:    50 Err:     0 (0.00%) Active: 2 Started: 2 Finished: 0\n" +
+		"summary +    200 in 00:00:30 =    6.7/s Avg:    20 Min:     2 Max:    60 Err:     1 (0.50%) Active: 4 Started: 4 Finished: 0\n" +
+		"summary =    300 in 00:01:00 =    5.0/s Avg:    16 Min:     1 Max:    60 Err:     1 (0.33%)\n")
+
+	if len(series.Intervals) != 2 {
+		t.Fatalf("got %d intervals, want 2", len(series.Intervals))
+	}
+	current, ok := series.Current()
+	if !ok || current.Samples != 200 || current.Active != 4 {
+		t.Errorf("current = %+v, want the latest interval", current)
+	}
+	total, ok := series.Cumulative()
+	if !ok || !total.Cumulative || total.Samples != 300 {
+		t.Errorf("cumulative = %+v, want the summary = line", total)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	tests := []struct {
+		name      string
+		summaries []Summary
+		want      Summary
+	}{
+		{
+			name: "none",
+		},
+		{
+			name: "intervals",
+			summaries: []Summary{
+				{Samples: 100, Elapsed: 30 * time.Second, Throughput: 3.5, AvgMs: 10, MinMs: 4, MaxMs: 50, Errors: 1, Active: 2},
+				{Samples: 300, Elapsed: 30 * time.Second, Throughput: 10, AvgMs: 30, MinMs: 2, MaxMs: 90, Errors: 3, Active: 6},
+			},
+			want: Summary{Samples: 400, Elapsed: 30 * time.Second, Throughput: 13.5, AvgMs: 25, MinMs: 2, MaxMs: 90, Errors: 4, Active: 8},
+		},
+		{
+			name: "cumulative",
+			summaries: []Summary{
+				{Cumulative: true, Samples: 1000, Elapsed: time.Minute, Throughput: 16, AvgMs: 20, MinMs: 1, MaxMs: 70},
+				{Cumulative: true, Samples: 1000, Elapsed: 2 * time.Minute, Throughput: 8, AvgMs: 40, MinMs: 3, MaxMs: 80, Errors: 20},
+			},
+			want: Summary{Cumulative: true, Samples: 2000, Elapsed: 2 * time.Minute, Throughput: 24, AvgMs: 30, MinMs: 1, MaxMs: 80, Errors: 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Aggregate(tt.summaries); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryErrorPct(t *testing.T) {
+	if pct := (Summary{Samples: 200, Errors: 5}).ErrorPct(); pct != 2.5 {
+		t.Errorf("got %v, want 2.5", pct)
+	}
+	if pct := (Summary{}).ErrorPct(); pct != 0 {
+		t.Errorf("got %v without samples, want 0", pct)
+	}
+}
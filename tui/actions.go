@@ -3,51 +3,61 @@ package tui
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
-	"os"
 	"path/filepath"
+	"slices"
 	"strings"
-	"sync"
 	"terminalui/kubeutils"
+	"terminalui/metrics"
 	"time"
 
-	v1 "k8s.io/api/core/v1"
-	"k8s.io/client-go/kubernetes"
+	tea "github.com/charmbracelet/bubbletea"
 )
 
+// staleThreshold is how many progress checks in a row may fail to read the run state
+// before the pod is given up on
 const staleThreshold = 5
 
 var errStale = errors.New("test is likely failed to finish. Check pod")
 
-func (m *ConfiguratorModel) getClusterConfig(kubeCtx string) (*kubeutils.Cluster, error) {
-	homeDir, _ := os.UserHomeDir()
-	defaultPath := filepath.Join(homeDir, ".kube", "config")
+func (m *ConfiguratorModel) getClusterConfig(kubeCtx string) (kubeutils.Backend, error) {
+	cfg := m.settings.Cluster
+	cfg.KubeCtxName = kubeCtx
+	cfg.PodPrefix = m.configForm.inputs[0].Value()
+	cfg.Namespace = m.configForm.inputs[1].Value()
+
+	if len(m.settings.Targets) > 0 {
+		return kubeutils.NewMultiCluster(cfg.PodPrefix, m.settings.Targets, func(t kubeutils.ClusterTarget, firstPod int) (kubeutils.Backend, error) {
+			targetCfg := cfg
+			targetCfg.KubeCtxName = t.KubeCtxName
+			targetCfg.Namespace = t.Namespace
+			targetCfg.FirstPod = firstPod
+			if m.settings.Simulate {
+				return kubeutils.NewSimulator(targetCfg, m.settings.Simulator, *m.logger)
+			}
+			return kubeutils.NewCluster(targetCfg, *m.logger)
+		})
+	}
 
-	config, err := kubeutils.BuildConfigWithContextFromFlags(kubeCtx, defaultPath)
-	if err != nil {
-		m.logger.Error("error creating Kubernetes client configuration: ", slog.Any("err", err))
-		return nil, err
+	if m.settings.Simulate {
+		return kubeutils.NewSimulator(cfg, m.settings.Simulator, *m.logger)
 	}
 
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		m.logger.Error("error creating Kubernetes client: ", slog.Any("err", err))
-		return nil, err
+	return kubeutils.NewCluster(cfg, *m.logger)
+}
+
+// getLocationInfo tells where the pods of the session run
+func (m *ConfiguratorModel) getLocationInfo() string {
+	if len(m.settings.Targets) == 0 {
+		return "Namespace: " + m.configForm.inputs[1].Value()
 	}
 
-	cluster := kubeutils.Cluster{
-		RestCfg:         config,
-		Clientset:       clientset,
-		PodPrefix:       m.configForm.inputs[0].Value(),
-		Namespace:       m.configForm.inputs[1].Value(),
-		KubeCtxName:     m.configForm.inputs[2].Value(),
-		PodKeepAliveSec: m.podKeepAliveSec,
-		PodsCache: &kubeutils.PodsCache{
-			Pods: make(map[string]*v1.Pod, len(m.pods)),
-		},
-		Logger: *m.logger,
+	targets := make([]string, len(m.settings.Targets))
+	for i, t := range m.settings.Targets {
+		targets[i] = fmt.Sprintf("%s (%d pods)", t, t.Pods)
 	}
-	return &cluster, nil
+	return "Targets: " + strings.Join(targets, ", ")
 }
 
 func (m *ConfiguratorModel) checkClusterConnection(ch chan<- ConfigDone) {
@@ -65,10 +75,19 @@ func (m *ConfiguratorModel) setupPods() {
 	m.preparation = pf
 	m.currentView = PreparePods
 
+	all := make([]int, len(m.pods))
+	for i := range all {
+		all[i] = i
+	}
+	m.preparePods(all, false)
+}
+
+// preparePods prepares the pods at indexes in the background, replace rolls back
+// what is left of an earlier attempt of them first
+func (m *ConfiguratorModel) preparePods(indexes []int, replace bool) {
 	go func() {
 		ch := make(chan kubeutils.ActionDone)
-		defer close(ch)
-		go m.beginPodsPreparation(ch)
+		go m.beginPodsPreparation(ch, indexes, replace)
 
 		for r := range ch {
 			m.Update(r)
@@ -77,30 +96,86 @@ func (m *ConfiguratorModel) setupPods() {
 	}()
 }
 
-func (m *ConfiguratorModel) beginPodsPreparation(ch chan<- kubeutils.ActionDone) {
-	var wg sync.WaitGroup
-	for _, pod := range m.preparation.pods {
-		wg.Add(1)
-		if m.preparation.err != "" {
-			break
-		}
-		go func(p PodInfo) {
-			defer wg.Done()
-			testInfo := kubeutils.TestInfo{
-				PodName:          p.name,
-				PropFileName:     p.propsFilePath,
-				ScenarioFileName: p.scenarioFilePath,
-			}
-			err := m.cluster.PreparePod(m.preparation.ctx, testInfo, ch)
-			if err != nil {
-				m.preparation.err = err.Error()
-				m.preparation.quitting = true
+// beginPodsPreparation prepares pods on a bounded amount of workers. A failed pod does not
+// stop the other ones, once all of them are done the user decides what happens to the failures.
+func (m *ConfiguratorModel) beginPodsPreparation(ch chan<- kubeutils.ActionDone, indexes []int, replace bool) {
+	defer close(ch)
+
+	p := m.preparation
+	stop := func() bool { return p.ctx.Err() != nil }
+	p.workers.run(len(indexes), stop, func(n int) {
+		i := indexes[n]
+		if replace {
+			rollbackStart := time.Now()
+			if err := m.cluster.RollbackPod(p.ctx, p.pods[i].name); err != nil {
+				ch <- kubeutils.ActionDone{PodName: p.pods[i].name, Name: "removing failed pod", Duration: time.Since(rollbackStart), Err: err}
+				p.setFailure(i, err)
 				return
 			}
-		}(pod)
+		}
+
+		testInfo := kubeutils.TestInfo{
+			PodName:          p.pods[i].name,
+			PropFileName:     p.pods[i].propsFilePath,
+			ScenarioFileName: p.pods[i].scenarioFilePath,
+		}
+		err := m.cluster.PreparePod(p.ctx, testInfo, ch)
+		if err != nil {
+			m.logger.Error("pod preparation failed", slog.Any("pod", p.pods[i].name), slog.Any("err", err.Error()))
+			p.setFailure(i, err)
+		}
+	})
+
+	failures := p.getFailures()
+	if len(failures) > 0 && p.ctx.Err() == nil {
+		healthy := len(p.pods) - len(failures)
+		// engines cannot run without the controller
+		_, controllerFailed := failures[0]
+		canContinue := healthy > 0 && !(m.settings.Cluster.Distributed && controllerFailed)
+		p.recovery = p.getRecoveryForm(canContinue, healthy)
 	}
-	wg.Wait()
-	m.preparation.quitting = true
+	p.quitting = true
+}
+
+// retryFailedPods prepares the failed pods again, in place of what is left of them
+func (m *ConfiguratorModel) retryFailedPods() {
+	p := m.preparation
+	failed := p.failedPods()
+
+	p.mu.Lock()
+	p.failures = make(map[int]error)
+	p.mu.Unlock()
+	p.quitting = false
+
+	m.logger.Info("retrying pod preparation", slog.Any("pods", len(failed)))
+	m.preparePods(failed, true)
+}
+
+// rollbackPods deletes every pod of the session after a failed preparation
+func (m *ConfiguratorModel) rollbackPods() {
+	p := m.preparation
+	p.rollingBack = true
+
+	stop := func() bool { return p.ctx.Err() != nil }
+	p.workers.run(len(p.pods), stop, func(i int) {
+		rollbackStart := time.Now()
+		err := m.cluster.RollbackPod(p.ctx, p.pods[i].name)
+		if err != nil {
+			m.logger.Error("pod rollback failed", slog.Any("pod", p.pods[i].name), slog.Any("err", err.Error()))
+			p.err = "some pods could not be deleted, check the cluster: " + err.Error()
+		}
+
+		m.Update(kubeutils.ActionDone{
+			PodName:  p.pods[i].name,
+			Name:     "pod has been rolled back",
+			Duration: time.Since(rollbackStart),
+			Err:      err,
+		})
+	})
+
+	m.logger.Info("pod preparation rolled back")
+	p.rollingBack = false
+	p.rolledBack = true
 }
 
 func (m *ConfiguratorModel) collectResults() {
@@ -121,23 +196,22 @@ func (m *ConfiguratorModel) collectResults() {
 }
 
 func (m *ConfiguratorModel) saveResults(ch chan<- kubeutils.ActionDone) {
-	var wg sync.WaitGroup
-	for _, pod := range m.run.pods {
-		wg.Add(1)
-		go func(p RunPodInfo) {
-			defer wg.Done()
-
-			testInfo := kubeutils.TestInfo{
-				PodName: p.name,
-			}
-			err := m.cluster.CollectResultsFromPod(m.preparation.ctx, testInfo, ch)
-			if err != nil {
-				m.resultsCollection.err = err
-				return
-			}
-		}(pod)
-	}
-	wg.Wait()
+	pods := m.run.pods
+	stop := func() bool { return m.resultsCollection.ctx.Err() != nil }
+	newWorkerPool(m.settings.Concurrency).run(len(pods), stop, func(i int) {
+		if pods[i].runState == Excluded {
+			return
+		}
+
+		testInfo := kubeutils.TestInfo{
+			PodName: pods[i].name,
+		}
+		err := m.cluster.CollectResultsFromPod(m.resultsCollection.ctx, testInfo, ch)
+		if err != nil {
+			m.resultsCollection.err = err
+			return
+		}
+	})
 	m.resultsCollection.isCollected = true
 	m.resultsCollection.showConfirmation = true
 }
@@ -146,6 +220,10 @@ func (m *ConfiguratorModel) startRun() {
 	m.run.runState = InProgress
 	m.run.showSpinner = true
 	for i, pod := range m.run.pods {
+		if pod.runState == Excluded {
+			continue
+		}
+
 		_, propFile := filepath.Split(pod.propsFilePath)
 		_, jmxFile := filepath.Split(pod.scenarioFilePath)
 
@@ -161,32 +239,69 @@ func (m *ConfiguratorModel) startRun() {
 		m.run.pods[i].runState = InProgress
 	}
 
+	m.monitorRun()
+}
+
+// monitorRun polls pods on every tick until all of them finished or the run is cancelled.
+// Only one sweep over the pods runs at a time, a tick arriving while one is still going is skipped.
+func (m *ConfiguratorModel) monitorRun() {
 	m.run.table = getPodsTable(m.run.pods)
 
-	duration := time.Duration(m.updateIntervalSec) * time.Second
+	duration := time.Duration(m.settings.UpdateIntervalSec) * time.Second
 	ticker := time.NewTicker(duration)
+	defer ticker.Stop()
 	updChannel := make(chan PodUpdate)
+	sweepDone := make(chan struct{})
+	sweeping := false
+
+	// a sweep still in flight is drained, so it does not block on updChannel forever
+	defer func() {
+		for sweeping {
+			select {
+			case <-updChannel:
+			case <-sweepDone:
+				sweeping = false
+			}
+		}
+	}()
 
 free:
 	for {
 		select {
 		case <-ticker.C:
 			m.logger.Info("TICK")
-			if m.run.runState == InProgress {
-				go m.checkIfRunComplete(m.ctx, m.run.pods, updChannel)
-			} else {
+			m.run.listener = getListenerTable(m.settings.Listener)
+			if m.run.runState != InProgress {
 				break free
 			}
+			if sweeping {
+				m.logger.Warn("previous progress check is still running, skipping tick")
+				continue
+			}
+
+			sweeping = true
+			pods := slices.Clone(m.run.pods)
+			go func() {
+				m.checkIfRunComplete(m.ctx, pods, updChannel)
+				sweepDone <- struct{}{}
+			}()
+		case <-sweepDone:
+			sweeping = false
+			// merging the histograms of all pods is too costly to repeat on every update
+			m.run.latency = getLatencyTable(m.run.pods)
 		case upd := <-updChannel:
 			m.logger.Info("Pod update event: ",
 				slog.Any("pod", m.run.pods[upd.podIndex].name),
 				slog.Any("state", upd.state),
 				slog.Any("stale for", upd.staleCounter))
 
-			m.run.pods[upd.podIndex].data.logs = upd.logs
+			m.run.pods[upd.podIndex].data.logs.Append(upd.logs)
+			m.run.pods[upd.podIndex].metrics.Ingest(upd.logs)
+			m.run.pods[upd.podIndex].results.Ingest(upd.results)
 			m.run.pods[upd.podIndex].data.staleFor = upd.staleCounter
 			m.run.pods[upd.podIndex].runState = upd.state
 			m.run.pods[upd.podIndex].err = upd.err
+			m.run.pods[upd.podIndex].trackResources(upd.resources, upd.hasResources, time.Now())
 
 			m.run.table = getPodsTable(m.run.pods)
 
@@ -197,7 +312,7 @@ free:
 				if pod.runState == Failed {
 					runHasFailedTests = true
 				}
-				if pod.runState != Completed && pod.runState != Failed {
+				if pod.runState != Completed && pod.runState != Failed && pod.runState != Excluded {
 					runIsFinished = false
 					break
 				}
@@ -214,11 +329,16 @@ free:
 	}
 
 	m.logger.Info("RUN COMPLETE")
+	m.run.latency = getLatencyTable(m.run.pods)
 	m.run.showSpinner = false
 }
 
 func (m ConfiguratorModel) checkIfRunComplete(ctx context.Context, pods []RunPodInfo, ch chan<- PodUpdate) {
 	for i, pod := range pods {
+		if pod.runState == Excluded {
+			continue
+		}
+
 		podUpd := PodUpdate{podIndex: i, inProgress: true, state: InProgress}
 
 		testInfo := kubeutils.TestInfo{
@@ -233,17 +353,33 @@ func (m ConfiguratorModel) checkIfRunComplete(ctx context.Context, pods []RunPod
 				slog.Any("err", err.Error()))
 		}
 
-		if strings.EqualFold(pod.data.logs, logs) || err != nil {
-			podUpd.staleCounter += 1
+		// a quiet log is no sign of trouble, JMeter writes a summary only every 30s and engines
+		// hardly log at all. The pod is stale when its run state cannot be read.
+		if err != nil && !isFinished {
+			podUpd.staleCounter = pod.data.staleFor + 1
 			m.logger.Warn("stale counter increased", slog.Any("pod", pod.name), slog.Any("cnt", podUpd.staleCounter))
 		}
 
-		if pod.data.staleFor > staleThreshold {
-			podUpd.err = errStale
+		if podUpd.staleCounter > staleThreshold {
+			podUpd.err = fmt.Errorf("%w: %w", errStale, err)
 			podUpd.inProgress = false
 			podUpd.state = Failed
 		}
 
+		// read after the progress check, so the final samples of a finished run are included
+		results, rErr := m.cluster.TailResults(ctx, testInfo)
+		if rErr != nil {
+			m.logger.Error("failed to read results", slog.Any("pod", pod.name), slog.Any("err", rErr.Error()))
+		}
+		podUpd.results = results
+
+		resources, resErr := m.cluster.GetPodResources(ctx, pod.name)
+		if resErr != nil && !errors.Is(resErr, kubeutils.ErrMetricsUnavailable) {
+			m.logger.Error("failed to read pod resources", slog.Any("pod", pod.name), slog.Any("err", resErr.Error()))
+		}
+		podUpd.resources = resources
+		podUpd.hasResources = resErr == nil
+
 		podUpd.logs = logs
 		if isFinished {
 			podUpd.inProgress = false
@@ -254,8 +390,81 @@ func (m ConfiguratorModel) checkIfRunComplete(ctx context.Context, pods []RunPod
 	}
 }
 
+// reattachToPods rebuilds the session from pods that are already running in the cluster
+// and continues from the Run view, or straight from results collection when every run is over
+func (m *ConfiguratorModel) reattachToPods() tea.Cmd {
+	discovered, err := m.cluster.DiscoverPods(m.ctx)
+	if err == nil && len(discovered) == 0 {
+		err = errors.New("no pods found for prefix " + m.configForm.inputs[0].Value())
+	}
+	if err != nil {
+		m.logger.Error("reattach failed", slog.Any("err", err.Error()))
+		m.configForm.err = err
+		m.configForm.connectionEstablished = false
+		return nil
+	}
+
+	m.pods = make([]PodInfo, len(discovered))
+	for i, d := range discovered {
+		m.pods[i] = PodInfo{
+			id:               i,
+			name:             d.Name,
+			scenarioFilePath: d.ScenarioFileName,
+			propsFilePath:    d.PropFileName,
+		}
+	}
+
+	m.run = m.InitRunView()
+	allFinished := true
+	anyInProgress := false
+	for i, d := range discovered {
+		switch {
+		case d.Err != nil:
+			m.run.pods[i].err = d.Err
+		case !d.JMeterInstalled:
+			m.run.pods[i].err = errors.New("JMeter is not installed in this pod")
+		case d.ScenarioFileName == "" || d.PropFileName == "":
+			m.run.pods[i].err = errors.New("test files are missing in this pod")
+		}
+
+		switch d.Run {
+		case kubeutils.RunInProgress:
+			m.run.pods[i].runState = InProgress
+			m.run.pods[i].data.logs.Set("reattached to a running test")
+			anyInProgress = true
+		case kubeutils.RunFinished:
+			m.run.pods[i].runState = Completed
+		}
+
+		if d.Run != kubeutils.RunFinished {
+			allFinished = false
+		}
+	}
+	m.run.table = getPodsTable(m.run.pods)
+	m.logger.Info("reattached to pods", slog.Any("count", len(discovered)), slog.Any("in progress", anyInProgress))
+
+	if allFinished {
+		m.run.runState = Done
+		m.collectResults()
+		return m.resultsCollection.spinner.Tick
+	}
+
+	m.currentView = Run
+	if anyInProgress {
+		m.run.runState = InProgress
+		m.run.showSpinner = true
+		go m.monitorRun()
+	}
+
+	return m.run.spinner.Tick
+}
+
 func (m *ConfiguratorModel) cancelRun() {
 	for i, pod := range m.pods {
+		if m.run.pods[i].runState == Excluded {
+			continue
+		}
+
 		testInfo := kubeutils.TestInfo{
 			PodName: pod.name,
 		}
@@ -271,15 +480,27 @@ func (m *ConfiguratorModel) cancelRun() {
 
 func (m *ConfiguratorModel) resetRun() {
 	for i, pod := range m.pods {
+		if m.run.pods[i].runState == Excluded {
+			continue
+		}
+
 		testInfo := kubeutils.TestInfo{
 			PodName: pod.name,
 		}
 		m.cluster.ResetPodForNewRun(m.ctx, testInfo)
 		m.run.pods[i].runState = NotStarted
-		m.run.pods[i].data.logs = "Pod is now ready for a new run"
+		m.run.pods[i].data.logs.Set("Pod is now ready for a new run")
+		m.run.pods[i].metrics.Reset()
+		m.run.pods[i].results = metrics.NewResultsStats()
+		m.run.pods[i].trackResources(kubeutils.PodResources{}, false, time.Now())
 	}
 
 	m.run.table = getPodsTable(m.run.pods)
+	m.run.latency = ""
+	if m.settings.Listener != nil {
+		m.settings.Listener.Reset()
+	}
+	m.run.listener = ""
 	m.run.runState = NotStarted
 	m.run.showSpinner = false
 }
@@ -287,29 +508,24 @@ func (m *ConfiguratorModel) resetRun() {
 func (m *ConfiguratorModel) deletePods() {
 	m.resultsCollection.showConfirmation = false
 
-	var wg sync.WaitGroup
-	for _, pod := range m.run.pods {
-		wg.Add(1)
-		go func(p RunPodInfo) {
-			defer wg.Done()
-			deleteStart := time.Now()
-			err := m.cluster.DeletePod(m.resultsCollection.ctx, p.name)
-			if err != nil {
-				m.logger.Error(err.Error())
-				m.resultsCollection.err = err
-				return
-			}
-			m.logger.Info("remove pod goroutine complete")
-
-			result := kubeutils.ActionDone{
-				PodName:  p.name,
-				Name:     "pod has been terminated",
-				Duration: time.Since(deleteStart),
-			}
-			m.Update(result)
-		}(pod)
-	}
+	pods := m.run.pods
+	stop := func() bool { return m.resultsCollection.ctx.Err() != nil }
+	newWorkerPool(m.settings.Concurrency).run(len(pods), stop, func(i int) {
+		deleteStart := time.Now()
+		err := m.cluster.DeletePod(m.resultsCollection.ctx, pods[i].name)
+		if err != nil {
+			m.logger.Error(err.Error())
+			m.resultsCollection.err = err
+			return
+		}
+		m.logger.Info("remove pod goroutine complete")
 
-	wg.Wait()
+		result := kubeutils.ActionDone{
+			PodName:  pods[i].name,
+			Name:     "pod has been terminated",
+			Duration: time.Since(deleteStart),
+		}
+		m.Update(result)
+	})
 	m.resultsCollection.quitting = true
 }
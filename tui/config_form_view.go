@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"terminalui/kubeutils"
 	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
@@ -17,6 +18,12 @@ func (m ConfiguratorModel) handleConfigFormView() string {
 	var b strings.Builder
 
 	b.WriteString(focusedStyle.Render("Set cluster config\n"))
+	if m.settings.Reattach {
+		b.WriteString(configInfoStyle.Render("Reattach mode: pods with the given prefix will be picked up\n"))
+	}
+	if len(m.settings.Targets) > 0 {
+		b.WriteString(configInfoStyle.Render("Namespace, context and amount of pods come from -targets\n"))
+	}
 
 	if m.configForm.err != nil {
 		b.WriteString("\nError: " + m.configForm.err.Error())
@@ -73,7 +80,14 @@ func (m *ConfiguratorModel) initConfigForm() {
 			t.CharLimit = 128
 		case 3:
 			t.Placeholder = "Amount of pods"
+			if m.settings.Reattach {
+				t.Placeholder = "Amount of pods (discovered when reattaching)"
+			}
 			t.Validate = func(input string) error {
+				if m.settings.Reattach && input == "" {
+					return nil
+				}
+
 				numPod, err := strconv.Atoi(input)
 				if err != nil {
 					m.configForm.err = errors.New("input must be a number")
@@ -94,6 +108,12 @@ func (m *ConfiguratorModel) initConfigForm() {
 		m.configForm.inputs[i] = t
 	}
 
+	if len(m.settings.Targets) > 0 {
+		m.configForm.inputs[1].SetValue("-")
+		m.configForm.inputs[2].SetValue("-")
+		m.configForm.inputs[3].SetValue(strconv.Itoa(kubeutils.TotalPods(m.settings.Targets)))
+	}
+
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Spinner.FPS = 200 * time.Millisecond
@@ -122,7 +142,7 @@ func (m *ConfiguratorModel) handleConfigFormUpdate(msg tea.Msg) (tea.Model, tea.
 				if m.configForm.inputs[0].Value() == "" ||
 					m.configForm.inputs[1].Value() == "" ||
 					m.configForm.inputs[2].Value() == "" ||
-					m.configForm.inputs[3].Value() == "" {
+					(m.configForm.inputs[3].Value() == "" && !m.settings.Reattach) {
 					m.configForm.err = errors.New("incorrect configuration")
 					return m, nil
 				}
@@ -182,6 +202,11 @@ func (m *ConfiguratorModel) handleConfigFormUpdate(msg tea.Msg) (tea.Model, tea.
 			return m, tea.Batch(cmds...)
 		}
 	case ConfigDone:
+		if m.settings.Reattach {
+			m.configForm.showSpinner = false
+			return m, m.reattachToPods()
+		}
+
 		totalPages, err := strconv.Atoi(m.configForm.inputs[3].Value())
 		if err != nil {
 			panic(err)
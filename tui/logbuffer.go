@@ -0,0 +1,41 @@
+package tui
+
+import "strings"
+
+// logBufferLines is how many of the latest log lines are kept for every pod
+const logBufferLines = 2000
+
+// logBuffer is a ring buffer keeping the latest lines of a pod log
+type logBuffer struct {
+	lines []string
+	// next is where the following line goes once the buffer is full
+	next int
+}
+
+// Append adds the lines of text, evicting the oldest ones when the buffer is full
+func (b *logBuffer) Append(text string) {
+	if text == "" {
+		return
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		if len(b.lines) < logBufferLines {
+			b.lines = append(b.lines, line)
+			continue
+		}
+		b.lines[b.next] = line
+		b.next = (b.next + 1) % logBufferLines
+	}
+}
+
+// Set replaces the whole content with text
+func (b *logBuffer) Set(text string) {
+	b.lines = nil
+	b.next = 0
+	b.Append(text)
+}
+
+func (b *logBuffer) String() string {
+	ordered := append(append([]string{}, b.lines[b.next:]...), b.lines[:b.next]...)
+	return strings.Join(ordered, "\n")
+}
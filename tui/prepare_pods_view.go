@@ -3,21 +3,57 @@ package tui
 import (
 	"context"
 	"fmt"
+	"maps"
+	"sort"
 	"strings"
 	"terminalui/kubeutils"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
 )
 
 func formatMsg(ad kubeutils.ActionDone) string {
 	if ad.Duration == 0 {
 		return dotStyle.Render(strings.Repeat(".", 30))
 	}
-	return fmt.Sprintf("* Pod: %s; Step: %s; Took: %s",
+	if ad.Retrying {
+		return fmt.Sprintf("* Pod: %s; Step: %s; Attempt %d failed, retrying: %s",
+			podLabelStyle.Render(ad.PodName),
+			stepNameStyle.Render(ad.Name),
+			ad.Attempts,
+			accentInfo.Render(ad.Err.Error()))
+	}
+	if ad.Err != nil {
+		return fmt.Sprintf("* Pod: %s; Step: %s; Failed: %s",
+			podLabelStyle.Render(ad.PodName),
+			stepNameStyle.Render(ad.Name),
+			accentInfo.Render(ad.Err.Error()))
+	}
+	msg := fmt.Sprintf("* Pod: %s; Step: %s; Took: %s",
 		podLabelStyle.Render(ad.PodName),
 		stepNameStyle.Render(ad.Name),
 		durationStyle.Render(ad.Duration.String()))
+	if ad.Bytes > 0 {
+		msg += "; Transferred: " + durationStyle.Render(formatBytes(ad.Bytes))
+	}
+	if ad.Attempts > 1 {
+		msg += fmt.Sprintf("; Attempts: %d", ad.Attempts)
+	}
+	return msg
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
 }
 
 func (m *ConfiguratorModel) InitPodsPreparation() *PreparePodsModel {
@@ -28,17 +64,92 @@ func (m *ConfiguratorModel) InitPodsPreparation() *PreparePodsModel {
 	prepareCtx, cancel := context.WithCancel(m.ctx)
 
 	pm := PreparePodsModel{
-		spinner: s,
-		results: make([]kubeutils.ActionDone, numLastResults),
-		pods:    m.pods,
-		logger:  m.logger,
-		ctx:     prepareCtx,
-		cancel:  cancel,
+		spinner:  s,
+		results:  make([]kubeutils.ActionDone, numLastResults),
+		pods:     m.pods,
+		workers:  newWorkerPool(m.settings.Concurrency),
+		failures: make(map[int]error),
+		logger:   m.logger,
+		ctx:      prepareCtx,
+		cancel:   cancel,
 	}
 
 	return &pm
 }
 
+// recovery choices offered once pods failed to prepare
+const (
+	recoverRollback = "rollback"
+	recoverRetry    = "retry"
+	recoverContinue = "continue"
+)
+
+func (p *PreparePodsModel) setFailure(podIndex int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures[podIndex] = err
+}
+
+// getFailures returns a copy of the failures, safe to read while pods are still being prepared
+func (p *PreparePodsModel) getFailures() map[int]error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return maps.Clone(p.failures)
+}
+
+// failedPods returns the indexes of the failed pods in order
+func (p *PreparePodsModel) failedPods() []int {
+	failures := p.getFailures()
+	failed := make([]int, 0, len(failures))
+	for i := range failures {
+		failed = append(failed, i)
+	}
+	sort.Ints(failed)
+	return failed
+}
+
+// getRecoveryForm offers to continue only with canContinue, when there are healthy pods left to run
+func (p *PreparePodsModel) getRecoveryForm(canContinue bool, healthy int) *huh.Form {
+	options := []huh.Option[string]{
+		huh.NewOption("Roll back: delete all pods of the session", recoverRollback),
+		huh.NewOption("Retry the failed pods", recoverRetry),
+	}
+	if canContinue {
+		options = append(options, huh.NewOption(fmt.Sprintf("Continue with %d healthy pods", healthy), recoverContinue))
+	}
+
+	return huh.NewForm(huh.NewGroup(huh.NewSelect[string]().
+		Title(accentInfo.Render("What would you like to do?")).
+		Options(options...).
+		Key("recovery")))
+}
+
+func (m *ConfiguratorModel) showRunView() tea.Cmd {
+	runView := m.InitRunView()
+	m.run = runView
+	m.currentView = Run
+	return m.run.spinner.Tick
+}
+
+func (m *ConfiguratorModel) handleRecoveryUpdate(msg tea.Msg) (tea.Model, tea.Cmd) {
+	form, cmd := m.preparation.recovery.Update(msg)
+	f, ok := form.(*huh.Form)
+	if !ok || f.State != huh.StateCompleted {
+		return m, cmd
+	}
+
+	m.preparation.recovery = nil
+	switch f.GetString("recovery") {
+	case recoverRollback:
+		go m.rollbackPods()
+	case recoverRetry:
+		m.retryFailedPods()
+	case recoverContinue:
+		return m, m.showRunView()
+	}
+	return m, m.preparation.spinner.Tick
+}
+
 func (m *ConfiguratorModel) handlePodsPreparationUpdate(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
@@ -46,6 +157,9 @@ func (m *ConfiguratorModel) handlePodsPreparationUpdate(msg tea.Msg) (tea.Model,
 			m.preparation.cancel()
 			return m, tea.Quit
 		}
+		if msg.String() == "c" && m.preparation.quitting && len(m.preparation.getFailures()) == 0 {
+			return m, m.showRunView()
+		}
 
 	case kubeutils.ActionDone:
 		m.preparation.results = append(m.preparation.results[1:], msg)
@@ -55,38 +169,58 @@ func (m *ConfiguratorModel) handlePodsPreparationUpdate(msg tea.Msg) (tea.Model,
 		m.preparation.spinner, cmd = m.preparation.spinner.Update(msg)
 		return m, cmd
 	default:
-		if m.preparation.quitting {
-			runView := m.InitRunView()
-			m.run = runView
-			m.currentView = Run
-			return m, m.run.spinner.Tick
+		if m.preparation.quitting && len(m.preparation.getFailures()) == 0 {
+			return m, m.showRunView()
 		}
 	}
 
+	if m.preparation.recovery != nil {
+		return m.handleRecoveryUpdate(msg)
+	}
+
 	return m, nil
 }
 
 func (m *ConfiguratorModel) handlePodsPreparationView() string {
 	var b strings.Builder
-
-	if m.preparation.quitting && m.preparation.err == "" {
+	p := m.preparation
+	failures := p.getFailures()
+
+	switch {
+	case p.rolledBack:
+		b.WriteString("Preparation has been rolled back, pods of the session are deleted.\n")
+		if p.err != "" {
+			b.WriteString(accentInfo.Render("\n" + p.err + "\n"))
+		}
+	case p.rollingBack:
+		b.WriteString(p.spinner.View() + " Rolling back pods...")
+		b.WriteString(configInfoStyle.Render(fmt.Sprintf(" queued: %d, active: %d\n",
+			p.workers.Queued(), p.workers.Active())))
+	case p.quitting && len(failures) == 0:
 		b.WriteString("Pods are now ready to run load tests!\n")
-	} else {
-		if m.preparation.err != "" {
-			b.WriteString(accentInfo.Render("\n" + m.preparation.err + "\n"))
+	case p.quitting:
+		b.WriteString(accentInfo.Render(fmt.Sprintf("%d of %d pods failed to prepare:", len(failures), len(p.pods))) + "\n")
+		for _, i := range p.failedPods() {
+			b.WriteString(fmt.Sprintf("* Pod: %s; %s\n", podLabelStyle.Render(p.pods[i].name), accentInfo.Render(failures[i].Error())))
 		}
-		b.WriteString(m.preparation.spinner.View() + " Preparing pods...\n")
+	default:
+		b.WriteString(p.spinner.View() + " Preparing pods...")
+		b.WriteString(configInfoStyle.Render(fmt.Sprintf(" queued: %d, active: %d, failed: %d\n",
+			p.workers.Queued(), p.workers.Active(), len(failures))))
 	}
 
-	for _, res := range m.preparation.results {
+	for _, res := range p.results {
 		b.WriteString(formatMsg(res) + "\n")
 	}
 
-	if !m.preparation.quitting {
+	switch {
+	case p.recovery != nil:
+		b.WriteString("\n" + p.recovery.View())
+	case p.rolledBack:
+		b.WriteString(alertStyle.Render("\nPress 'ctrl+c' to exit"))
+	case !p.quitting:
 		b.WriteString(helpStyle.Render("\n\n\nPods are being prepared..."))
-	}
-
-	if m.preparation.quitting {
+	case len(failures) == 0:
 		b.WriteString(alertStyle.Render("\nPress 'c' to continue... "))
 	}
 
@@ -0,0 +1,52 @@
+package tui
+
+import (
+	"fmt"
+	"terminalui/kubeutils"
+	"time"
+)
+
+// a load generator is flagged as saturated when its CPU stays this close to the limit for this long
+const (
+	cpuSaturationPct    = 90
+	cpuSaturationPeriod = 30 * time.Second
+)
+
+// trackResources records the latest usage of a pod and since when its CPU is near the limit
+func (p *RunPodInfo) trackResources(usage kubeutils.PodResources, ok bool, now time.Time) {
+	p.resources = usage
+	p.hasResources = ok
+	if !ok || usage.CPUPct() < cpuSaturationPct {
+		p.cpuHotSince = time.Time{}
+		return
+	}
+	if p.cpuHotSince.IsZero() {
+		p.cpuHotSince = now
+	}
+}
+
+func (p *RunPodInfo) isCPUSaturated(now time.Time) bool {
+	return !p.cpuHotSince.IsZero() && now.Sub(p.cpuHotSince) >= cpuSaturationPeriod
+}
+
+// getResourceColumns renders CPU and memory usage against the limits
+func getResourceColumns(p RunPodInfo, now time.Time) []string {
+	if !p.hasResources {
+		return []string{"n/a", "n/a"}
+	}
+
+	r := p.resources
+	cpu := fmt.Sprintf("%dm", r.CPUMilli)
+	if r.CPULimitMilli > 0 {
+		cpu = fmt.Sprintf("%dm / %dm (%.0f%%)", r.CPUMilli, r.CPULimitMilli, r.CPUPct())
+	}
+	if p.isCPUSaturated(now) {
+		cpu = "! " + cpu
+	}
+
+	memory := formatBytes(r.MemoryBytes)
+	if r.MemoryLimitBytes > 0 {
+		memory = fmt.Sprintf("%s / %s (%.0f%%)", memory, formatBytes(r.MemoryLimitBytes), r.MemoryPct())
+	}
+	return []string{cpu, memory}
+}
@@ -1,8 +1,12 @@
 package tui
 
 import (
+	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"terminalui/metrics"
+	"time"
 
 	"github.com/charmbracelet/bubbles/paginator"
 	"github.com/charmbracelet/bubbles/spinner"
@@ -30,12 +34,21 @@ func (cm *ConfiguratorModel) handleRunView() string {
 
 	if m.isTableView {
 		b.WriteString("\n" + m.table)
+		if m.latency != "" {
+			b.WriteString("\n" + m.latency)
+		}
+		if m.listener != "" {
+			b.WriteString("\n" + m.listener)
+		}
 	} else {
 		b.WriteString(podLogsStyle.Render("\n" + m.podViews[m.currentPod].View()))
 	}
 
 	b.WriteString("\nCurrent run state: " + m.runState.String())
-	b.WriteString(configInfoStyle.Render("\nNamespace: " + m.namespace))
+	b.WriteString(configInfoStyle.Render("\n" + m.location))
+	if excluded := getExcludedInfo(m.pods); excluded != "" {
+		b.WriteString(alertStyle.Render("\n" + excluded))
+	}
 
 	start, end := m.pages.GetSliceBounds(len(m.pods))
 	for _, item := range m.pods[start:end] {
@@ -96,7 +109,16 @@ func (cm *ConfiguratorModel) handleRunViewUpdate(msg tea.Msg) (tea.Model, tea.Cm
 		m.pages = updatedPaginator
 
 		if !m.isTableView {
-			m.podViews[m.currentPod].SetContent(m.pods[m.currentPod].data.logs)
+			// keep following new lines unless the log was scrolled up
+			followLogs := m.podViews[m.currentPod].AtBottom()
+			logs := m.pods[m.currentPod].data.logs.String()
+			if logs == "" {
+				logs = "no logs yet"
+			}
+			m.podViews[m.currentPod].SetContent(logs)
+			if followLogs {
+				m.podViews[m.currentPod].GotoBottom()
+			}
 			updatedPodView, podViewCmd := m.podViews[m.currentPod].Update(msg)
 			m.podViews[m.currentPod] = updatedPodView
 
@@ -153,8 +175,7 @@ func (m *TestRunModel) Init() tea.Cmd {
 }
 
 func (m *ConfiguratorModel) InitRunView() *TestRunModel {
-	podsAmount, _ := strconv.Atoi(m.configForm.inputs[3].Value())
-	namespace := m.configForm.inputs[1].Value()
+	podsAmount := len(m.pods)
 
 	p := paginator.New()
 	p.Type = paginator.Dots
@@ -168,6 +189,12 @@ func (m *ConfiguratorModel) InitRunView() *TestRunModel {
 	s.Spinner = spinner.Meter
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("155"))
 
+	// pods that failed to prepare are only there when the user chose to continue without them
+	var excluded map[int]error
+	if m.preparation != nil {
+		excluded = m.preparation.getFailures()
+	}
+
 	var loadTestPods []RunPodInfo
 	var podViews []viewport.Model
 	for i := range podsAmount {
@@ -177,11 +204,17 @@ func (m *ConfiguratorModel) InitRunView() *TestRunModel {
 				name:             m.pods[i].name,
 				scenarioFilePath: m.pods[i].scenarioFilePath,
 				propsFilePath:    m.pods[i].propsFilePath,
-				data:             PodLogs{logs: "no logs yet"},
 			},
 			runState:   NotStarted,
 			err:        nil,
 			resultPath: "",
+			results:    metrics.NewResultsStats(),
+			target:     m.cluster.PodTarget(m.pods[i].name),
+		}
+		if err, ok := excluded[i]; ok {
+			tPod.runState = Excluded
+			tPod.err = fmt.Errorf("failed to prepare: %w", err)
+			tPod.data.logs.Set("Pod is excluded from the run, it failed to prepare: " + err.Error())
 		}
 		vp := viewport.New(200, viewportHeight)
 		vp.MouseWheelEnabled = true
@@ -192,7 +225,7 @@ func (m *ConfiguratorModel) InitRunView() *TestRunModel {
 
 	runModel := &TestRunModel{
 		runState:    NotStarted,
-		namespace:   namespace,
+		location:    m.getLocationInfo(),
 		pods:        loadTestPods,
 		isTableView: true,
 		currentPod:  0,
@@ -226,6 +259,8 @@ func (state TestRunState) String() string {
 		stateStr = accentInfo.Render("run failed")
 	case Done:
 		stateStr = completedStyle.Render("done")
+	case Excluded:
+		stateStr = accentInfo.Render("excluded")
 	default:
 		stateStr = "Unknown state"
 	}
@@ -233,18 +268,85 @@ func (state TestRunState) String() string {
 	return stateStr
 }
 
+// getTableRows renders a row per pod and the total. When pods run on several targets,
+// every target is followed by its own total.
 func getTableRows(pods []RunPodInfo) [][]string {
-	rows := make([][]string, len(pods))
+	now := time.Now()
+	grouped := false
+	for _, pod := range pods {
+		grouped = grouped || pod.target != pods[0].target
+	}
+
+	rows := make([][]string, 0, len(pods)+1)
+	var current, cumulative []metrics.Summary
+	var groupCurrent, groupCumulative []metrics.Summary
 	for i, row := range pods {
 		rowErr := "-"
 		if row.err != nil {
 			rowErr = row.err.Error()
 		}
-		tRow := []string{row.name, row.runState.String(), rowErr}
-		rows[i] = tRow
+
+		cur, hasCur := row.metrics.Current()
+		total, hasTotal := row.metrics.Cumulative()
+		if hasCur {
+			current = append(current, cur)
+			groupCurrent = append(groupCurrent, cur)
+		}
+		if hasTotal {
+			cumulative = append(cumulative, total)
+			groupCumulative = append(groupCumulative, total)
+		}
+
+		tRow := []string{row.name, row.runState.String()}
+		tRow = append(tRow, getMetricsColumns(cur, hasCur, total, hasTotal)...)
+		tRow = append(tRow, getResourceColumns(row, now)...)
+		rows = append(rows, append(tRow, rowErr))
+
+		if grouped && (i == len(pods)-1 || pods[i+1].target != row.target) {
+			rows = append(rows, getTotalRow(row.target+" total", groupCurrent, groupCumulative))
+			groupCurrent, groupCumulative = nil, nil
+		}
+	}
+
+	return append(rows, getTotalRow("total", current, cumulative))
+}
+
+// getExcludedInfo names the pods the session continued without
+func getExcludedInfo(pods []RunPodInfo) string {
+	var excluded []string
+	for _, pod := range pods {
+		if pod.runState == Excluded {
+			excluded = append(excluded, pod.name)
+		}
+	}
+	if len(excluded) == 0 {
+		return ""
 	}
 
-	return rows
+	return fmt.Sprintf("Excluded %d of %d pods that failed to prepare: %s. Totals cover the other pods only",
+		len(excluded), len(pods), strings.Join(excluded, ", "))
+}
+
+func getTotalRow(name string, current, cumulative []metrics.Summary) []string {
+	totalRow := []string{name, "-"}
+	totalRow = append(totalRow, getMetricsColumns(
+		metrics.Aggregate(current), len(current) > 0,
+		metrics.Aggregate(cumulative), len(cumulative) > 0)...)
+	return append(totalRow, "-", "-", "-")
+}
+
+// getMetricsColumns renders current and cumulative throughput, average latency and error percentage
+func getMetricsColumns(cur metrics.Summary, hasCur bool, total metrics.Summary, hasTotal bool) []string {
+	cols := []string{"-", "-", "-", "-"}
+	if hasCur {
+		cols[0] = fmt.Sprintf("%.1f/s", cur.Throughput)
+	}
+	if hasTotal {
+		cols[1] = fmt.Sprintf("%.1f/s", total.Throughput)
+		cols[2] = fmt.Sprintf("%d ms", total.AvgMs)
+		cols[3] = fmt.Sprintf("%.2f%%", total.ErrorPct())
+	}
+	return cols
 }
 
 func getPodsTable(pods []RunPodInfo) string {
@@ -253,8 +355,127 @@ func getPodsTable(pods []RunPodInfo) string {
 		Border(lipgloss.ThickBorder()).
 		BorderStyle(tableBorderStyle).
 		BorderRow(true).
-		Headers("Pod", "State", "Error").
-		Width(100).
+		Headers("Pod", "State", "RPS now", "RPS total", "Avg latency", "Err %", "CPU", "Memory", "Error").
+		Width(160).
+		Rows(rows...)
+
+	now := time.Now()
+	var saturated []string
+	for _, pod := range pods {
+		if pod.isCPUSaturated(now) {
+			saturated = append(saturated, pod.name)
+		}
+	}
+	if len(saturated) == 0 {
+		return t.Render()
+	}
+
+	return t.Render() + alertStyle.Render(fmt.Sprintf(
+		"\n! CPU above %d%% of the limit for over %s: %s. Results may be skewed by the load generator",
+		cpuSaturationPct, cpuSaturationPeriod, strings.Join(saturated, ", ")))
+}
+
+// getLatencyTable renders percentiles per sampler label over the samples of all pods
+func getLatencyTable(pods []RunPodInfo) string {
+	merged := metrics.NewResultsStats()
+	for _, pod := range pods {
+		merged.Merge(pod.results)
+	}
+	if merged.All.Count() == 0 {
+		return ""
+	}
+
+	row := func(label string, h *metrics.Histogram) []string {
+		return []string{
+			label,
+			strconv.FormatInt(h.Count(), 10),
+			fmt.Sprintf("%d ms", h.Quantile(0.50)),
+			fmt.Sprintf("%d ms", h.Quantile(0.90)),
+			fmt.Sprintf("%d ms", h.Quantile(0.95)),
+			fmt.Sprintf("%d ms", h.Quantile(0.99)),
+			fmt.Sprintf("%d ms", h.Max()),
+		}
+	}
+
+	var rows [][]string
+	for _, label := range merged.Labels() {
+		rows = append(rows, row(label, merged.ByLabel[label]))
+	}
+	rows = append(rows, row("all", merged.All))
+
+	t := table.New().
+		Border(lipgloss.ThickBorder()).
+		BorderStyle(tableBorderStyle).
+		Headers("Label", "Samples", "p50", "p90", "p95", "p99", "Max").
+		Width(130).
+		Rows(rows...)
+
+	codes := make([]string, 0, len(merged.ErrorsByCode))
+	for code := range merged.ErrorsByCode {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	errorsByCode := "none"
+	if len(codes) > 0 {
+		parts := make([]string, len(codes))
+		for i, code := range codes {
+			parts[i] = fmt.Sprintf("%s: %d", code, merged.ErrorsByCode[code])
+		}
+		errorsByCode = strings.Join(parts, ", ")
+	}
+
+	return t.Render() + fmt.Sprintf("\nActive threads: %d | Errors by code: %s", merged.ActiveThreads, errorsByCode)
+}
+
+// getListenerTable renders the latest Backend Listener window of every pod and their total
+func getListenerTable(receiver *metrics.Receiver) string {
+	if receiver == nil {
+		return ""
+	}
+	snapshot := receiver.Snapshot()
+	if len(snapshot) == 0 {
+		return ""
+	}
+
+	row := func(name string, s metrics.ListenerStats) []string {
+		return []string{
+			name,
+			fmt.Sprintf("%.1f/s", s.Throughput),
+			fmt.Sprintf("%.0f ms", s.AvgMs),
+			fmt.Sprintf("%.0f ms", s.Pct90Ms),
+			fmt.Sprintf("%.0f ms", s.Pct95Ms),
+			fmt.Sprintf("%.0f ms", s.Pct99Ms),
+			fmt.Sprintf("%.2f%%", s.ErrorPct),
+			strconv.Itoa(s.ActiveThreads),
+		}
+	}
+
+	// percentiles of different pods do not add up, the total shows the worst one
+	var total metrics.ListenerStats
+	var errorWeight float64
+	var rows [][]string
+	for _, s := range snapshot {
+		rows = append(rows, row(s.Application, s))
+		total.AvgMs += s.AvgMs * s.Throughput
+		errorWeight += s.ErrorPct * s.Throughput
+		total.Throughput += s.Throughput
+		total.Pct90Ms = max(total.Pct90Ms, s.Pct90Ms)
+		total.Pct95Ms = max(total.Pct95Ms, s.Pct95Ms)
+		total.Pct99Ms = max(total.Pct99Ms, s.Pct99Ms)
+		total.ActiveThreads += s.ActiveThreads
+	}
+	if total.Throughput > 0 {
+		total.AvgMs /= total.Throughput
+		total.ErrorPct = errorWeight / total.Throughput
+	}
+	rows = append(rows, row("Total", total))
+
+	t := table.New().
+		Border(lipgloss.ThickBorder()).
+		BorderStyle(tableBorderStyle).
+		Headers("Backend listener", "RPS", "Avg", "p90", "p95", "p99", "Err %", "Threads").
+		Width(130).
 		Rows(rows...)
 
 	return t.Render()
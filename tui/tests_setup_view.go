@@ -12,13 +12,12 @@ import (
 
 func (m *ConfiguratorModel) handleTestsSetupView() string {
 	var b strings.Builder
-	namespace := m.configForm.inputs[1].Value()
 
 	b.WriteString(focusedStyle.Render("\nPrepare pods"))
 	if m.err != nil {
 		b.WriteString(accentInfo.Render("\n\n Error: " + m.err.Error() + "\n"))
 	}
-	b.WriteString(configInfoStyle.Render("\nNamespace: " + namespace))
+	b.WriteString(configInfoStyle.Render("\n" + m.getLocationInfo()))
 	start, end := m.paginator.GetSliceBounds(len(m.pods))
 	for _, item := range m.pods[start:end] {
 		sf := alertStyle.Render("not set")
@@ -36,6 +35,9 @@ func (m *ConfiguratorModel) handleTestsSetupView() string {
 
 		b.WriteString(configInfoStyle.Render("\nPod name" + divider))
 		b.WriteString(podLabelStyle.Render(item.name))
+		if len(m.settings.Targets) > 0 {
+			b.WriteString(configInfoStyle.Render("\nTarget" + divider + m.cluster.PodTarget(item.name)))
+		}
 		b.WriteString("\n" + m.paginator.View())
 	}
 
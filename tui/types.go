@@ -3,7 +3,10 @@ package tui
 import (
 	"context"
 	"log/slog"
+	"sync"
 	"terminalui/kubeutils"
+	"terminalui/metrics"
+	"time"
 
 	"github.com/charmbracelet/bubbles/filepicker"
 	"github.com/charmbracelet/bubbles/paginator"
@@ -21,6 +24,23 @@ type PodInfo struct {
 	scenarioFilePath string
 }
 
+// AppSettings holds the startup options passed in from the command line.
+// Cluster is completed with the values entered in the config form.
+type AppSettings struct {
+	UpdateIntervalSec int
+	Cluster           kubeutils.ClusterConfig
+	Simulate          bool
+	Simulator         kubeutils.SimulatorConfig
+	// Reattach picks up pods of an earlier session instead of creating new ones
+	Reattach bool
+	// Listener receives Backend Listener metrics from the pods, nil when disabled
+	Listener *metrics.Receiver
+	// Targets spread the session over several contexts and namespaces instead of the ones in the form
+	Targets []kubeutils.ClusterTarget
+	// Concurrency is how many pods are prepared, collected or deleted at once
+	Concurrency int
+}
+
 type ConfigDone struct {
 	ConnectionOk bool
 }
@@ -28,7 +48,7 @@ type ConfigDone struct {
 type PodLogs struct {
 	// How many consecutive checks logs remain unchanged
 	staleFor int
-	logs     string
+	logs     logBuffer
 }
 
 type ConfigViewModel struct {
@@ -46,6 +66,15 @@ type PreparePodsModel struct {
 	results  []kubeutils.ActionDone
 	quitting bool
 	err      string
+	workers  *workerPool
+
+	mu sync.Mutex
+	// failures holds the error of every pod whose preparation failed, by pod index
+	failures map[int]error
+	// recovery asks whether to roll back, retry the failed pods or continue without them
+	recovery    *huh.Form
+	rollingBack bool
+	rolledBack  bool
 
 	logger *slog.Logger
 	ctx    context.Context
@@ -76,14 +105,13 @@ type ConfirmationModel struct {
 }
 
 type ConfiguratorModel struct {
-	ctx               context.Context
-	logger            *slog.Logger
-	currentView       AppViewState
-	pods              []PodInfo
-	podKeepAliveSec   int
-	updateIntervalSec int
+	ctx         context.Context
+	logger      *slog.Logger
+	currentView AppViewState
+	pods        []PodInfo
+	settings    AppSettings
 
-	cluster           *kubeutils.Cluster
+	cluster           kubeutils.Backend
 	paginator         *paginator.Model
 	filepicker        *FilePickerModule
 	setupConfirmation *ConfirmationModel
@@ -96,7 +124,7 @@ type ConfiguratorModel struct {
 
 type TestRunModel struct {
 	runState     TestRunState
-	namespace    string
+	location     string
 	pods         []RunPodInfo
 	isTableView  bool
 	currentPod   int
@@ -104,6 +132,8 @@ type TestRunModel struct {
 	pages        paginator.Model
 	confirm      *huh.Form
 	table        string
+	latency      string
+	listener     string
 	spinner      spinner.Model
 	showSpinner  bool
 	isConfirmed  bool
@@ -124,6 +154,11 @@ type PodUpdate struct {
 	inProgress   bool
 	state        TestRunState
 	err          error
+	// results are JTL lines written since the previous update
+	results string
+
+	resources    kubeutils.PodResources
+	hasResources bool
 }
 
 type RunPodInfo struct {
@@ -132,6 +167,15 @@ type RunPodInfo struct {
 	runState   TestRunState
 	err        error
 	resultPath string
+	metrics    metrics.PodSeries
+	results    *metrics.ResultsStats
+	// target is where the pod runs, see Backend.PodTarget
+	target string
+
+	resources    kubeutils.PodResources
+	hasResources bool
+	// cpuHotSince is when CPU usage went near the limit, zero while it is below
+	cpuHotSince time.Time
 }
 
 type ClearErrorMsg struct{}
@@ -161,4 +205,6 @@ const (
 	ResetConfirm
 	Failed
 	Done
+	// Excluded pods failed to prepare and the session continued without them
+	Excluded
 )
@@ -10,16 +10,19 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-func loadTestConfiguratorModel(appCtx context.Context, appLogger *slog.Logger, updateIntervalSec int, podKeepAliveSec int) *ConfiguratorModel {
+func loadTestConfiguratorModel(appCtx context.Context, appLogger *slog.Logger, settings AppSettings) *ConfiguratorModel {
 	m := ConfiguratorModel{
-		ctx:               appCtx,
-		logger:            appLogger,
-		updateIntervalSec: updateIntervalSec,
-		podKeepAliveSec:   podKeepAliveSec,
-		currentView:       Config}
+		ctx:         appCtx,
+		logger:      appLogger,
+		settings:    settings,
+		currentView: Config}
 
 	m.initConfigForm()
-	m.logger.Info("First form initiated", slog.Any("pod keep alive", podKeepAliveSec), slog.Any("upd interval", updateIntervalSec))
+	m.logger.Info("First form initiated",
+		slog.Any("pod keep alive", settings.Cluster.PodKeepAliveSec),
+		slog.Any("image", settings.Cluster.Image),
+		slog.Any("upd interval", settings.UpdateIntervalSec),
+		slog.Any("simulate", settings.Simulate))
 
 	return &m
 }
@@ -82,10 +85,10 @@ func (m *ConfiguratorModel) View() string {
 	}
 }
 
-func DisplayUI(ctx context.Context, logger *slog.Logger, updateIntervalSec int, podKeepAliveSec int) {
+func DisplayUI(ctx context.Context, logger *slog.Logger, settings AppSettings) {
 	logger.Info("Loading UI...")
 	configurationProgram := tea.NewProgram(
-		loadTestConfiguratorModel(ctx, logger, updateIntervalSec, podKeepAliveSec),
+		loadTestConfiguratorModel(ctx, logger, settings),
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion())
 
@@ -0,0 +1,60 @@
+package tui
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultConcurrency is how many pods are prepared, collected or deleted at once unless configured otherwise
+const DefaultConcurrency = 10
+
+// workerPool runs a task per pod with at most size of them at once, counting queued and active ones
+type workerPool struct {
+	size   int
+	queued atomic.Int32
+	active atomic.Int32
+}
+
+func newWorkerPool(size int) *workerPool {
+	return &workerPool{size: max(size, 1)}
+}
+
+// run calls task for every index below n and waits until all of them are done.
+// Tasks that did not start yet are dropped once stop returns true.
+func (p *workerPool) run(n int, stop func() bool, task func(i int)) {
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, p.size)
+	p.queued.Store(int32(n))
+
+	for i := range n {
+		slots <- struct{}{}
+		p.queued.Add(-1)
+		if stop() {
+			<-slots
+			continue
+		}
+
+		p.active.Add(1)
+		wg.Add(1)
+		go func() {
+			defer func() {
+				p.active.Add(-1)
+				<-slots
+				wg.Done()
+			}()
+			task(i)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// Queued is how many tasks wait for a free worker
+func (p *workerPool) Queued() int {
+	return int(p.queued.Load())
+}
+
+// Active is how many tasks are running
+func (p *workerPool) Active() int {
+	return int(p.active.Load())
+}